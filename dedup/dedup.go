@@ -0,0 +1,181 @@
+// Package dedup merges the same advertisement heard by multiple gateways
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Observation of the advertisement by a gateway
+type Observation struct {
+	// Gateway (IGSXX) mac address
+	Gateway string
+	// RSSI value
+	RSSI int
+	// Timestamp in message, or the received time if gateway sends none
+	Timestamp time.Time
+}
+
+// Advertisement merged from the messages of multiple gateways
+type Merged struct {
+	// The first received message
+	Message *igs.Message
+	// Beacon (Tag) BLE mac address
+	Beacon string
+	// BLE payload in HEX string
+	Payload string
+	// Observations of each gateway, in the order of received
+	Observations []Observation
+
+	start time.Time // time of Deduplicator clock the window started
+}
+
+// Timestamp of the first observation
+func (m *Merged) First() time.Time {
+	return m.Observations[0].Timestamp
+}
+
+// Returns the observation with the strongest RSSI
+func (m *Merged) Strongest() Observation {
+	best := m.Observations[0]
+	for _, o := range m.Observations[1:] {
+		if o.RSSI > best.RSSI {
+			best = o
+		}
+	}
+	return best
+}
+
+type key struct {
+	beacon  string
+	payload string
+}
+
+// Deduplicator merges messages with the same beacon mac address and payload
+// within a time window, safe for concurrent use.
+// Windows are measured by the clock of Deduplicator rather than the timestamps in messages,
+// which are from the clocks of gateways and not in sync.
+type Deduplicator struct {
+	// Clock of windows, time.Now if nil. Set it before adding any message,
+	// e.g. to the time of message for replaying a recorded stream.
+	// Windows are ended in order of start, the clock should not go backwards.
+	Clock func() time.Time
+
+	window  time.Duration
+	emit    func(*Merged)
+	mu      sync.Mutex
+	pending map[key]*Merged
+	queue   []*Merged // pending ones in order of start
+}
+
+// Deduplicator constructor, emit is called for each merged advertisement
+// once its window (started by the first message) ends
+func New(window time.Duration, emit func(*Merged)) *Deduplicator {
+	return &Deduplicator{
+		window:  window,
+		emit:    emit,
+		pending: map[key]*Merged{},
+	}
+}
+
+func (d *Deduplicator) now() time.Time {
+	if d.Clock != nil {
+		return d.Clock()
+	}
+	return time.Now()
+}
+
+// Add a message, only the first message of each gateway is taken in a window.
+// Windows ended at the time of adding are emitted first.
+// Mac addresses are normalized by igs.NormalizeMAC.
+func (d *Deduplicator) Add(m *igs.Message) {
+	beacon, _ := igs.NormalizeMAC(m.Beacon())
+	gateway, _ := igs.NormalizeMAC(m.Gateway())
+	o := Observation{gateway, m.RSSI(), m.Time()}
+	k := key{beacon, m.Payload()}
+
+	d.mu.Lock()
+	now := d.now()
+	expired := d.expired(now)
+	if merged, ok := d.pending[k]; !ok {
+		merged = &Merged{
+			Message:      m,
+			Beacon:       beacon,
+			Payload:      m.Payload(),
+			Observations: []Observation{o},
+			start:        now,
+		}
+		d.pending[k] = merged
+		d.queue = append(d.queue, merged)
+	} else if !merged.observed(gateway) {
+		merged.Observations = append(merged.Observations, o)
+	}
+	d.mu.Unlock()
+	d.emitAll(expired)
+}
+
+func (m *Merged) observed(gateway string) bool {
+	for _, o := range m.Observations {
+		if o.Gateway == gateway {
+			return true
+		}
+	}
+	return false
+}
+
+// Emit merged advertisements with window ended at the time now of Clock
+func (d *Deduplicator) Expire(now time.Time) {
+	d.mu.Lock()
+	x := d.expired(now)
+	d.mu.Unlock()
+	d.emitAll(x)
+}
+
+// Emit all pending merged advertisements
+func (d *Deduplicator) Flush() {
+	d.mu.Lock()
+	x := d.queue
+	d.queue = nil
+	d.pending = map[key]*Merged{}
+	d.mu.Unlock()
+	d.emitAll(x)
+}
+
+// Remove the ones with window ended from the front of queue, d.mu must be held
+func (d *Deduplicator) expired(now time.Time) []*Merged {
+	n := 0
+	for n < len(d.queue) && now.Sub(d.queue[n].start) >= d.window {
+		delete(d.pending, key{d.queue[n].Beacon, d.queue[n].Payload})
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	x := d.queue[:n:n]
+	d.queue = d.queue[n:]
+	return x
+}
+
+func (d *Deduplicator) emitAll(x []*Merged) {
+	for _, m := range x {
+		d.emit(m)
+	}
+}
+
+// Expire periodically until ctx is done, then flush all pending ones
+func (d *Deduplicator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.Expire(d.now())
+		case <-ctx.Done():
+			d.Flush()
+			return
+		}
+	}
+}
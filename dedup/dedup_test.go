@@ -0,0 +1,123 @@
+package dedup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+const (
+	payload1 = "02010612FF0D0083BC3101006D0B31000000140F0600"
+	payload2 = "02010612FF0D0083BC3101006E0B31000000140F0600"
+)
+
+func TestDeduplicator(t *testing.T) {
+	var merged []*Merged
+	d := New(500*time.Millisecond, func(m *Merged) {
+		merged = append(merged, m)
+	})
+	lines := []string{
+		"$GPRP,D8714D784B4F,F008D1789200,-60," + payload1 + ",1630382368.000",
+		"$GPRP,D8714D784B4F,F008D1789201,-45," + payload1 + ",1630382368.100",
+		"$GPRP,D8714D784B40,F008D1789201,-70," + payload1 + ",1630382368.150",
+		"$GPRP,D8714D784B4F,F008D1789202,-50," + payload1 + ",1630382368.200",
+		"$GPRP,D8714D784B4F,F008D1789201,-40," + payload1 + ",1630382368.300",
+		"$GPRP,D8714D784B4F,F008D1789200,-61," + payload2 + ",1630382368.400",
+		// window of first advertisement ends
+		"$GPRP,D8714D784B4F,F008D1789200,-62," + payload1 + ",1630382368.600",
+	}
+	var now time.Time
+	d.Clock = func() time.Time { return now }
+	for _, line := range lines {
+		m := igs.Parse(line)
+		now = m.Time()
+		d.Add(m)
+	}
+	if len(merged) != 1 {
+		t.Fatalf("emitted %v before flush, want 1", len(merged))
+	}
+	want := []Observation{
+		{"F008D1789200", -60, time.Unix(1630382368, 0)},
+		{"F008D1789201", -45, time.Unix(1630382368, 100000000)},
+		{"F008D1789202", -50, time.Unix(1630382368, 200000000)},
+	}
+	if m := merged[0]; m.Beacon != "D8714D784B4F" || m.Payload != payload1 || !reflect.DeepEqual(m.Observations, want) {
+		t.Errorf("merged[0] = %+v", m)
+	}
+	if s := merged[0].Strongest(); s.Gateway != "F008D1789201" {
+		t.Errorf("merged[0].Strongest() = %+v", s)
+	}
+
+	d.Flush()
+	if len(merged) != 4 {
+		t.Fatalf("emitted %v after flush, want 4", len(merged))
+	}
+	if m := merged[1]; m.Beacon != "D8714D784B40" || len(m.Observations) != 1 {
+		t.Errorf("merged[1] = %+v", m)
+	}
+	if merged[2].Payload != payload2 || merged[3].Payload != payload1 || merged[3].Observations[0].RSSI != -62 {
+		t.Errorf("merged[2:] = %+v %+v", merged[2], merged[3])
+	}
+}
+
+func TestDeduplicator_Run(t *testing.T) {
+	emitted := make(chan *Merged, 1)
+	d := New(10*time.Millisecond, func(m *Merged) { emitted <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx, time.Millisecond)
+	d.Add(igs.Parse("$GPRP,D8714D784B4F,F008D1789200,-60," + payload1))
+	select {
+	case m := <-emitted:
+		if m.Beacon != "D8714D784B4F" {
+			t.Errorf("emitted %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("not emitted")
+	}
+}
+
+func TestDeduplicator_ClockSkew(t *testing.T) {
+	var merged []*Merged
+	d := New(500*time.Millisecond, func(m *Merged) {
+		merged = append(merged, m)
+	})
+	now := time.Unix(1700000000, 0)
+	d.Clock = func() time.Time { return now }
+	// gateway clocks are an hour behind and ahead
+	d.Add(igs.Parse("$GPRP,D8714D784B4F,F008D1789200,-60," + payload1 + ",1699996400.000"))
+	d.Add(igs.Parse("$GPRP,D8714D784B40,F008D1789201,-60," + payload1 + ",1700003600.000"))
+	now = now.Add(100 * time.Millisecond)
+	d.Add(igs.Parse("$GPRP,D8714D784B4F,F008D1789201,-50," + payload1 + ",1700003600.100"))
+	d.Expire(now)
+	if len(merged) != 0 {
+		t.Fatalf("emitted %v within window, want 0", len(merged))
+	}
+	d.Expire(now.Add(400 * time.Millisecond))
+	if len(merged) != 2 {
+		t.Fatalf("emitted %v after window, want 2", len(merged))
+	}
+	for _, m := range merged {
+		if m.Beacon == "D8714D784B4F" && (len(m.Observations) != 2 || !m.First().Equal(time.Unix(1699996400, 0))) {
+			t.Errorf("merged = %+v", m)
+		}
+	}
+}
+
+func TestDeduplicator_LowerCaseMAC(t *testing.T) {
+	var merged []*Merged
+	d := New(time.Second, func(m *Merged) {
+		merged = append(merged, m)
+	})
+	d.Add(igs.Parse("$GPRP,D8714D784B4F,F008D1789200,-60," + payload1))
+	d.Add(igs.Parse("$GPRP,d8714d784b4f,f008d1789200,-50," + payload1))
+	d.Add(igs.Parse("$GPRP,d8714d784b4f,f008d1789201,-50," + payload1))
+	d.Flush()
+	if len(merged) != 1 || merged[0].Beacon != "D8714D784B4F" || len(merged[0].Observations) != 2 ||
+		merged[0].Observations[1].Gateway != "F008D1789201" {
+		t.Errorf("merged = %+v", merged)
+	}
+}
@@ -1,11 +1,9 @@
 package main
 
 import (
-	"encoding/hex"
 	"fmt"
 	"os"
 
-	"github.com/ingics/ingics-parser-go/ibs"
 	"github.com/ingics/ingics-parser-go/igs"
 )
 
@@ -18,7 +16,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	if m := igs.Parse(os.Args[1]); m != nil {
+	if m, err := igs.ParseE(os.Args[1]); err == nil {
 		fmt.Printf("Type:    %v\n", m.MsgType())
 		fmt.Printf("Beacon:  %v\n", m.Beacon())
 		fmt.Printf("Gateway: %v\n", m.Gateway())
@@ -28,12 +26,14 @@ func main() {
 			fmt.Printf("Time:    %v\n", t)
 		}
 
-		if bytes, err := hex.DecodeString(m.Payload()); err == nil {
-			p := ibs.Parse(bytes)
-			fmt.Printf("Payload(parsed): %v\n", p)
+		if r, err := m.Decode(); err == nil {
+			fmt.Printf("Payload(parsed): %v\n", r.Payload)
+		} else {
+			fmt.Println(err)
 		}
 	} else {
 		fmt.Println("Error: Invalid input message")
 		fmt.Println(os.Args[1])
+		fmt.Println(err)
 	}
 }
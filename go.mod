@@ -1,8 +1,10 @@
 module github.com/ingics/ingics-parser-go
 
-go 1.13
+go 1.18
 
 require (
 	github.com/go-ble/ble v0.0.0-20210519192345-b055c211937b
 	github.com/google/uuid v1.6.0
 )
+
+require github.com/pkg/errors v0.8.1 // indirect
@@ -0,0 +1,204 @@
+package ibs
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/go-ble/ble"
+	"github.com/go-ble/ble/linux/adv"
+)
+
+// Decoder of BLE payload, decoded readings are stored by Payload.SetReadings
+type Decoder interface {
+	// Decode the payload, returns false if the payload is not recognized
+	Decode(payload *Payload) bool
+}
+
+// Function adapter of Decoder
+type DecoderFunc func(payload *Payload) bool
+
+func (f DecoderFunc) Decode(payload *Payload) bool {
+	return f(payload)
+}
+
+// Matcher selects the payloads passed to a decoder
+type Matcher func(payload *Payload) bool
+
+// Matches payloads with manufacturer data of the vendor codes
+func ManufacturerID(codes ...uint16) Matcher {
+	return func(payload *Payload) bool {
+		if code, ok := payload.VendorCode(); ok {
+			for _, c := range codes {
+				if code == c {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Matches payloads with service data of the UUIDs
+func ServiceUUID(uuids ...ble.UUID) Matcher {
+	return func(payload *Payload) bool {
+		for _, sd := range payload.ServiceData() {
+			for _, u := range uuids {
+				if sd.UUID.Equal(u) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Built-in decoder of Ingics iBS beacons
+var IngicsDecoder Decoder = DecoderFunc(func(payload *Payload) bool {
+	ok, err := payload.ibs()
+	if err != nil {
+		payload.SetError(err)
+	}
+	return ok
+})
+
+// Built-in decoder of Apple iBeacon
+var AppleDecoder Decoder = DecoderFunc(func(payload *Payload) bool {
+	return payload.apple()
+})
+
+type registration struct {
+	name     string
+	priority int
+	match    Matcher
+	decoder  Decoder
+}
+
+// Parser decodes payloads with the registered decoders, safe for concurrent use.
+// Decoders are tried in order of priority (higher first), then in order of registration,
+// until one of them recognizes the payload.
+type Parser struct {
+	mu      sync.RWMutex
+	entries []registration
+}
+
+// Parser constructor, without any decoder registered
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Register a decoder, match selects the payloads passed to it (all payloads if nil)
+func (ps *Parser) Register(name string, priority int, match Matcher, decoder Decoder) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	// a new slice, the current one may be in use by ParseE
+	entries := make([]registration, len(ps.entries), len(ps.entries)+1)
+	copy(entries, ps.entries)
+	entries = append(entries, registration{name, priority, match, decoder})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].priority > entries[j].priority
+	})
+	ps.entries = entries
+}
+
+// Unregister the decoders of the name, returns false if not registered
+func (ps *Parser) Unregister(name string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	entries := make([]registration, 0, len(ps.entries))
+	for _, e := range ps.entries {
+		if e.name != name {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == len(ps.entries) {
+		return false
+	}
+	ps.entries = entries
+	return true
+}
+
+// Names of registered decoders, in the order of trying
+func (ps *Parser) Decoders() []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	x := make([]string, len(ps.entries))
+	for i, e := range ps.entries {
+		x[i] = e.name
+	}
+	return x
+}
+
+// Input payload ([]byte) and returns the Payload instense
+func (ps *Parser) Parse(bytes []byte) *Payload {
+	payload, _ := ps.ParseE(bytes)
+	return payload
+}
+
+// Same as Parse but returns the *ParseError if the payload is malformed
+// or not fully decoded, the partially decoded payload is returned as well
+func (ps *Parser) ParseE(bytes []byte) (*Payload, error) {
+	payload := &Payload{
+		Packet: *adv.NewRawPacket(bytes),
+		msdata: map[string]interface{}{},
+		custom: map[string]interface{}{},
+	}
+	ps.mu.RLock()
+	entries := ps.entries
+	ps.mu.RUnlock()
+	for _, e := range entries {
+		if e.match != nil && !e.match(payload) {
+			continue
+		}
+		if e.decoder.Decode(payload) {
+			payload.decoder = e.name
+			break
+		}
+		// errors of unrecognized payload are not concerned
+		payload.err = nil
+	}
+	if payload.err == nil {
+		payload.err = validateAD(bytes)
+	}
+	return payload, payload.err
+}
+
+// Check the advertising data structures, each one is length byte followed by type and data
+func validateAD(b []byte) error {
+	for offset := 0; offset < len(b); {
+		l := int(b[offset])
+		if l == 0 {
+			// early termination, remaining bytes are zero padding
+			break
+		}
+		if offset+1+l > len(b) {
+			return &ParseError{"ad", offset, ErrMalformed}
+		}
+		offset += 1 + l
+	}
+	return nil
+}
+
+// Parser used by Parse, with the built-in decoders registered in priority 0
+var DefaultParser = NewParser()
+
+func init() {
+	DefaultParser.Register("ingics", 0, hasProductCode, IngicsDecoder)
+	DefaultParser.Register("apple", 0, ManufacturerID(0x004C), AppleDecoder)
+}
+
+// Ingics beacons carry the product code after the vendor code
+func hasProductCode(payload *Payload) bool {
+	msd := payload.ManufacturerData()
+	return len(msd) >= 4 && binary.LittleEndian.Uint16(msd[2:4])&0xFF00 == 0xBC00
+}
+
+// Register a decoder to DefaultParser, see Parser.Register
+func Register(name string, priority int, match Matcher, decoder Decoder) {
+	DefaultParser.Register(name, priority, match, decoder)
+}
+
+// Unregister decoders from DefaultParser, see Parser.Unregister
+func Unregister(name string) bool {
+	return DefaultParser.Unregister(name)
+}
@@ -0,0 +1,140 @@
+package ibs
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-ble/ble"
+)
+
+// in-house sensor with vendor code 0xFFFF: temperature in 0.01 C
+var testDecoder = DecoderFunc(func(payload *Payload) bool {
+	msd := payload.ManufacturerData()
+	if len(msd) != 4 {
+		return false
+	}
+	temp := float32(int16(binary.LittleEndian.Uint16(msd[2:4]))) / 100
+	payload.SetProduct("In-house", "T1")
+	payload.SetReadings(Readings{Temperature: &temp})
+	payload.Set("raw", msd[2:4])
+	return true
+})
+
+func TestParser(t *testing.T) {
+	ps := NewParser()
+	ps.Register("apple", 0, ManufacturerID(0x004C), AppleDecoder)
+	ps.Register("ingics", 0, nil, IngicsDecoder)
+	ps.Register("in-house", 10, ManufacturerID(0xFFFF), testDecoder)
+	if got, want := ps.Decoders(), []string{"in-house", "apple", "ingics"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Decoders() = %v, want %v", got, want)
+	}
+
+	b, _ := hex.DecodeString("02010605FFFFFF2A09")
+	got := ps.Parse(b)
+	if name := got.Decoder(); name != "in-house" {
+		t.Errorf("Decoder() = %q, want %q", name, "in-house")
+	}
+	validateFieldFunc(t, got, "Vendor", "In-house")
+	validateFieldFunc(t, got, "ProductModel", "T1")
+	validateFieldFunc(t, got, "Temperature", float32(23.46))
+	if v, ok := got.Get("raw"); !ok || !reflect.DeepEqual(v, []byte{0x2A, 0x09}) {
+		t.Errorf("Get(raw) = %v, %v", v, ok)
+	}
+
+	// built-in decoders
+	b, _ = hex.DecodeString("0201061AFF4C000215E2C56DB5DFFB48D2B060D0F5A71096E000000000C5")
+	if name := ps.Parse(b).Decoder(); name != "apple" {
+		t.Errorf("Decoder() = %q, want %q", name, "apple")
+	}
+	b, _ = hex.DecodeString("02010612FF0D0083BC3101006D0B31000000140F0600")
+	if name := ps.Parse(b).Decoder(); name != "ingics" {
+		t.Errorf("Decoder() = %q, want %q", name, "ingics")
+	}
+	if name := Parse(b).Decoder(); name != "ingics" {
+		t.Errorf("Decoder() = %q, want %q", name, "ingics")
+	}
+
+	// not recognized
+	b, _ = hex.DecodeString("02010605FFFEFF2A09")
+	got = ps.Parse(b)
+	if name := got.Decoder(); name != "" {
+		t.Errorf("Decoder() = %q, want %q", name, "")
+	}
+	validateFieldFunc(t, got, "Temperature", nil)
+}
+
+func TestParser_ServiceUUID(t *testing.T) {
+	ps := NewParser()
+	ps.Register("eddystone", 0, ServiceUUID(ble.UUID16(0xFEAA)), DecoderFunc(func(payload *Payload) bool {
+		v := int(payload.ServiceData()[0].Data[0])
+		payload.SetReadings(Readings{Value: &v})
+		return true
+	}))
+	b, _ := hex.DecodeString("0201060516AAFE1234")
+	validateFieldFunc(t, ps.Parse(b), "Value", 0x12)
+	b, _ = hex.DecodeString("0201060516ABFE1234")
+	validateFieldFunc(t, ps.Parse(b), "Value", nil)
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", -1, ManufacturerID(0xFFFF), testDecoder)
+	b, _ := hex.DecodeString("02010605FFFFFF2A09")
+	if name := Parse(b).Decoder(); name != "test" {
+		t.Errorf("Decoder() = %q, want %q", name, "test")
+	}
+	if !Unregister("test") || Unregister("test") {
+		t.Errorf("Unregister(test) is not once")
+	}
+	if name := Parse(b).Decoder(); name != "" {
+		t.Errorf("Decoder() = %q, want %q", name, "")
+	}
+	if got, want := DefaultParser.Decoders(), []string{"ingics", "apple"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Decoders() = %v, want %v", got, want)
+	}
+}
+
+func TestParser_Concurrent(t *testing.T) {
+	ps := NewParser()
+	ps.Register("ingics", 0, nil, IngicsDecoder)
+	b, _ := hex.DecodeString("02010612FF0D0083BC3101006D0B31000000140F0600")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ps.Register("test", i%3-1, ManufacturerID(0xFFFF), testDecoder)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if name := ps.Parse(b).Decoder(); name != "ingics" {
+			t.Fatalf("Decoder() = %q, want %q", name, "ingics")
+		}
+	}
+	<-done
+}
+
+func TestSetReadings(t *testing.T) {
+	payload := Parse([]byte{0x02, 0x01, 0x06})
+	temp, counter, lux, button := float32(23.5), 7, uint(300), true
+	if err := payload.SetReadings(Readings{Temperature: &temp, Counter: &counter, Lux: &lux, Button: &button}); err != nil {
+		t.Fatalf("SetReadings() error = %v", err)
+	}
+	validateFieldFunc(t, payload, "Temperature", temp)
+	validateFieldFunc(t, payload, "Counter", counter)
+	validateFieldFunc(t, payload, "Lux", lux)
+	validateFieldFunc(t, payload, "ButtonPressed", true)
+	validateFieldFunc(t, payload, "Humidity", nil)
+
+	// nothing stored if any reading does not fit
+	humidity, counter, co2 := float32(40), 70000, -1
+	for _, r := range []Readings{{Humidity: &humidity, Counter: &counter}, {Humidity: &humidity, CO2: &co2}} {
+		if err := payload.SetReadings(r); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("SetReadings() error = %v, want ErrOutOfRange", err)
+		}
+	}
+	validateFieldFunc(t, payload, "Counter", 7)
+	validateFieldFunc(t, payload, "CO2", nil)
+	validateFieldFunc(t, payload, "Humidity", nil)
+}
@@ -0,0 +1,64 @@
+package ibs
+
+import (
+	"encoding/hex"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// Seed corpus, hex strings of payloads in payload_test.go
+func fuzzSeeds(f *testing.F) [][]byte {
+	src, err := os.ReadFile("payload_test.go")
+	if err != nil {
+		f.Fatal(err)
+	}
+	var seeds [][]byte
+	for _, s := range regexp.MustCompile(`"((?:[0-9A-F]{2}){3,})"`).FindAllStringSubmatch(string(src), -1) {
+		if b, err := hex.DecodeString(s[1]); err == nil {
+			seeds = append(seeds, b)
+		}
+	}
+	return seeds
+}
+
+func FuzzParse(f *testing.F) {
+	for _, b := range fuzzSeeds(f) {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p, err := ParseE(b)
+		if p == nil {
+			t.Fatal("ParseE() = nil")
+		}
+		if p.Err() != err {
+			t.Errorf("Err() = %v, want %v", p.Err(), err)
+		}
+		if p.Partial() && p.Decoder() == "" {
+			t.Error("Partial() without decoder")
+		}
+		_ = p.String()
+		checkAccessors(t, p)
+	})
+}
+
+// Accessors in form of func() (value, ok bool) return zero (or empty) value if not ok
+func checkAccessors(t *testing.T, p *Payload) {
+	v := reflect.ValueOf(p)
+	typ := v.Type()
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		if m.Type.NumIn() != 1 || m.Type.NumOut() != 2 || m.Type.Out(1).Kind() != reflect.Bool {
+			continue
+		}
+		out := v.Method(i).Call(nil)
+		if out[1].Bool() {
+			continue
+		}
+		value := out[0]
+		if !value.IsZero() && !((value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() == 0) {
+			t.Errorf("%v() = %v, false", m.Name, value)
+		}
+	}
+}
@@ -83,9 +83,12 @@ var evtMasks = map[string]uint8{
 	evtFlip:   uint8(1 << bitFlip),
 }
 
-func (pkt Payload) ibs() bool {
+func (pkt Payload) ibs() (bool, error) {
 	if mfg, ok := pkt.VendorCode(); ok {
-		msd := pkt.Packet.ManufacturerData()
+		msd := pkt.ManufacturerData()
+		if len(msd) < 4 {
+			return false, nil
+		}
 		code := binary.LittleEndian.Uint16(msd[2:4])
 		if mfg == 0x59 && code == 0xBC80 {
 			// iBS01(H/G/T)
@@ -97,7 +100,7 @@ func (pkt Payload) ibs() bool {
 				[]string{fieldBattAct, fieldAccels},
 				[]string{},
 			}
-			return pkt.parsePayload(rgPayloadDef)
+			return true, pkt.parsePayload(rgPayloadDef)
 		} else if code == 0xBC82 {
 			// iBS02 for RS
 			return pkt.parsePayloadBySubtype(13, rsPayloadDefs)
@@ -111,7 +114,7 @@ func (pkt Payload) ibs() bool {
 				[]string{fieldBattAct, fieldAccels, fieldGP},
 				[]string{},
 			}
-			return pkt.parsePayload(gpPayloadDef)
+			return true, pkt.parsePayload(gpPayloadDef)
 		} else if mfg == 0x082C && code == 0x0BC86 {
 			// iBS05RG
 			var gpPayloadDef = payloadDef{
@@ -119,7 +122,7 @@ func (pkt Payload) ibs() bool {
 				[]string{fieldBattAct, fieldAccels},
 				[]string{},
 			}
-			return pkt.parsePayload(gpPayloadDef)
+			return true, pkt.parsePayload(gpPayloadDef)
 		} else if mfg == 0x082C && code == 0xBC83 {
 			// iBS05/iBS06
 			return pkt.parsePayloadBySubtype(13, ibsCommonPayloadDefs)
@@ -131,24 +134,27 @@ func (pkt Payload) ibs() bool {
 			return pkt.parsePayloadBySubtype(21, ibsBC88PayloadDefs)
 		}
 	}
-	return false
+	return false, nil
 }
 
-func (pkt Payload) ibs01() bool {
+func (pkt Payload) ibs01() (bool, error) {
 	if mfg, ok := pkt.VendorCode(); ok {
 		msd := pkt.ManufacturerData()
 		typ := binary.LittleEndian.Uint16(msd[2:4])
 		if mfg == 0x59 && typ == 0xBC80 {
+			pkt.msdata[fieldVendor] = knownVendorCode[IngicsVendorCode]
+			if len(msd) < 14 {
+				return true, &ParseError{fieldSubtype, 13, ErrTruncated}
+			}
 			subtype := uint8(msd[13])
 			if subtype == 0xff || subtype == 0x00 {
 				// old firmware without subtype
-				pkt.msdata[fieldVendor] = knownVendorCode[IngicsVendorCode]
 				pkt.handleFloatField(fieldBattery, 4)
-				if len(msd) > 7 && msd[7] != 0xFF && msd[8] != 0xFF {
+				if msd[7] != 0xFF && msd[8] != 0xFF {
 					// has temperature value, should be iBS01T
 					pkt.msdata[fieldModel] = "iBS01T"
 					pkt.handleFloatField(fieldTemperature, 7)
-					pkt.handleIntField(fieldHumidity, 9)
+					pkt.handleHumidityInt(fieldHumidity, 9)
 				} else {
 					// others, cannot detect the 'read' model from payload
 					// list all possible sensor fields
@@ -159,13 +165,13 @@ func (pkt Payload) ibs01() bool {
 					pkt.msdata[evtHall] = flags&evtMasks[evtHall] != 0
 					pkt.msdata[evtFall] = flags&evtMasks[evtFall] != 0
 				}
-				return true
+				return true, nil
 			} else {
 				return pkt.parsePayloadBySubtype(13, ibs01PayloadDefs)
 			}
 		}
 	}
-	return false
+	return false, nil
 }
 
 func (pkt Payload) handleIntField(name string, index int) int {
@@ -173,10 +179,6 @@ func (pkt Payload) handleIntField(name string, index int) int {
 	unsignedValue := binary.LittleEndian.Uint16(msd[index : index+2])
 	if unsignedValue != 0xAAAA {
 		pkt.msdata[name] = int16(unsignedValue)
-		// special handling for humidity, convert to float
-		if name == fieldHumidity {
-			pkt.msdata[name] = float32(pkt.msdata[name].(int16))
-		}
 	}
 	return index + 2
 }
@@ -216,6 +218,15 @@ func (pkt Payload) handleHumidity(name string, index int) int {
 	return index + 2
 }
 
+// humidity of iBS01T old firmware, 0xAAAA if not present
+func (pkt Payload) handleHumidityInt(name string, index int) int {
+	pkt.handleIntField(name, index)
+	if v, ok := pkt.msdata[name].(int16); ok {
+		pkt.msdata[name] = float32(v)
+	}
+	return index + 2
+}
+
 func (pkt Payload) handleHumidity1D(name string, index int) int {
 	pkt.handleUintField(fieldHumidity, index)
 	if v, ok := pkt.msdata[fieldHumidity].(uint16); ok {
@@ -307,7 +318,7 @@ func (pkt Payload) handleRsEventsField(name string, index int) int {
 	value := uint8(msd[index])
 	pkt.msdata[fieldEvents] = value
 	pkt.msdata[evtDin] = (value & 0x04) != 0
-	return 1
+	return index + 1
 }
 
 // special handler for RG models,
@@ -581,8 +592,43 @@ var ibsBC88PayloadDefs = map[byte]payloadDef{
 	},
 }
 
-// Parse the payload follow the input definition
-func (pkt Payload) parsePayload(def payloadDef) bool {
+// Size (in bytes) of payload fields
+var fieldSizes = map[string]int{
+	fieldBattery:     2,
+	fieldTemperature: 2,
+	fieldTempExt:     2,
+	fieldTempEnv:     2,
+	fieldHumidity:    2,
+	fieldHumidity1D:  2,
+	fieldSubtype:     1,
+	fieldEvents:      1,
+	fieldAccel:       6,
+	fieldAccels:      18,
+	fieldRange:       2,
+	fieldCO2:         2,
+	fieldCounter:     2,
+	fieldUserData:    2,
+	fieldReserved:    1,
+	fieldReserved2:   2,
+	fieldGP:          2,
+	fieldBattAct:     2,
+	fieldRsEvents:    1,
+	fieldLux:         2,
+	fieldVoltage:     2,
+	fieldCurrent:     2,
+	fieldValue:       2,
+	fieldPm2p5:       2,
+	fieldPm10p0:      2,
+	fieldVoc:         2,
+	fieldNox:         2,
+	fieldAux1:        2,
+	fieldAux2:        2,
+	fieldAux3:        2,
+}
+
+// Parse the payload follow the input definition,
+// fields are decoded until the manufacturer data is truncated
+func (pkt Payload) parsePayload(def payloadDef) error {
 	// define field handlers
 	pkt.msdata[fieldVendor] = knownVendorCode[IngicsVendorCode]
 	var fieldDefs = map[string]func(string, int) int{
@@ -623,28 +669,37 @@ func (pkt Payload) parsePayload(def payloadDef) bool {
 	} else if meth, ok := def.model.(func() string); ok {
 		pkt.msdata[fieldModel] = meth()
 	}
+	var err error
+	msd := pkt.ManufacturerData()
 	index := 4
 	for _, fieldName := range def.fields {
 		if fieldMethod, ok := fieldDefs[fieldName]; ok {
+			if index+fieldSizes[fieldName] > len(msd) {
+				err = &ParseError{fieldName, index, ErrTruncated}
+				break
+			}
 			index = fieldMethod(fieldName, index)
 		}
 	}
-	if value, ok := pkt.msdata[fieldEvents]; ok && len(def.events) > 0 {
+	if value, ok := pkt.msdata[fieldEvents].(uint8); ok && len(def.events) > 0 {
 		for _, evt := range def.events {
-			pkt.msdata[evt] = value.(uint8)&evtMasks[evt] != 0
+			pkt.msdata[evt] = value&evtMasks[evt] != 0
 		}
 	}
-	return true
+	return err
 }
 
 // Parse the payload by checkout 'subtype' first
 // Will find definition by subtype in the input 'payloadDefs'
-func (pkt Payload) parsePayloadBySubtype(subTypeIdx int, payloadDefs map[byte]payloadDef) bool {
+func (pkt Payload) parsePayloadBySubtype(subTypeIdx int, payloadDefs map[byte]payloadDef) (bool, error) {
 	msd := pkt.ManufacturerData()
 	pkt.msdata[fieldVendor] = knownVendorCode[IngicsVendorCode]
+	if len(msd) <= subTypeIdx {
+		return true, &ParseError{fieldSubtype, subTypeIdx, ErrTruncated}
+	}
 	subtype := uint8(msd[subTypeIdx])
 	if def, ok := payloadDefs[subtype]; ok {
-		return pkt.parsePayload(def)
+		return true, pkt.parsePayload(def)
 	}
-	return false
+	return false, nil
 }
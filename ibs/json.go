@@ -0,0 +1,217 @@
+package ibs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Version of the JSON schema of Payload
+const JSONVersion = 1
+
+// Units of readings in JSON, unitless readings are not listed
+var readingUnits = map[string]string{
+	fieldBattery:     "V",
+	fieldTemperature: "°C",
+	fieldTempExt:     "°C",
+	fieldTempEnv:     "°C",
+	fieldHumidity:    "%",
+	fieldCO2:         "ppm",
+	fieldVoltage:     "mV",
+	fieldCurrent:     "µA",
+	fieldLux:         "lx",
+	fieldPm2p5:       "µg/m³",
+	fieldPm10p0:      "µg/m³",
+}
+
+type readingJSON struct {
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+type accelJSON struct {
+	X int16 `json:"x"`
+	Y int16 `json:"y"`
+	Z int16 `json:"z"`
+}
+
+type iBeaconJSON struct {
+	UUID  string `json:"uuid"`
+	Major uint   `json:"major"`
+	Minor uint   `json:"minor"`
+	RefTx int    `json:"ref_tx"`
+}
+
+type payloadJSON struct {
+	Version  int                    `json:"version"`
+	Raw      string                 `json:"raw"`
+	Decoder  string                 `json:"decoder,omitempty"`
+	Vendor   string                 `json:"vendor,omitempty"`
+	Model    string                 `json:"model,omitempty"`
+	Readings map[string]readingJSON `json:"readings,omitempty"`
+	Events   map[string]bool        `json:"events,omitempty"`
+	IBeacon  *iBeaconJSON           `json:"ibeacon,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// JSON marshaler of Payload, keys are in fixed order. Schema version 1:
+//
+//	{
+//	  "version": 1,                          // JSONVersion
+//	  "raw": "02010612FF0D00...",            // payload in HEX string
+//	  "decoder": "ingics",                   // decoder recognized the payload, if any
+//	  "vendor": "INGICS TECHNOLOGY CO., LTD.",
+//	  "model": "iBS03T",
+//	  "readings": {                          // present readings, keyed by name
+//	    "battery": {"value": 3.05, "unit": "V"},
+//	    "accel": {"value": {"x": 0, "y": 36, "z": -260}}
+//	  },
+//	  "events": {"button": false},           // present event stats
+//	  "ibeacon": {"uuid": "...", "major": 0, "minor": 0, "ref_tx": -59},
+//	  "error": "ibs: ..."                    // why the payload is not fully decoded, if any
+//	}
+func (payload Payload) MarshalJSON() ([]byte, error) {
+	v := payloadJSON{
+		Version: JSONVersion,
+		Raw:     strings.ToUpper(hex.EncodeToString(payload.Packet.Bytes())),
+		Decoder: payload.decoder,
+	}
+	v.Vendor, _ = payload.Vendor()
+	v.Model, _ = payload.ProductModel()
+	if payload.err != nil {
+		v.Error = payload.err.Error()
+	}
+
+	r := payload.Readings()
+	readings := map[string]readingJSON{}
+	reading := func(name string, value interface{}) {
+		readings[name] = readingJSON{value, readingUnits[name]}
+	}
+	for _, x := range []struct {
+		name  string
+		value *float32
+	}{
+		{fieldBattery, r.Battery},
+		{fieldTemperature, r.Temperature},
+		{fieldTempExt, r.TemperatureExt},
+		{fieldTempEnv, r.TemperatureEnv},
+		{fieldHumidity, r.Humidity},
+		{fieldGP, r.GP},
+		{fieldPm2p5, r.PM2p5},
+		{fieldPm10p0, r.PM10p0},
+		{fieldVoc, r.VOC},
+		{fieldNox, r.NOx},
+	} {
+		if x.value != nil {
+			reading(x.name, *x.value)
+		}
+	}
+	for _, x := range []struct {
+		name  string
+		value *int
+	}{
+		{fieldRange, r.Range},
+		{fieldCounter, r.Counter},
+		{fieldCO2, r.CO2},
+		{fieldVoltage, r.Voltage},
+		{fieldValue, r.Value},
+		{fieldUserData, r.UserData},
+		{fieldAux1, r.Aux1},
+		{fieldAux2, r.Aux2},
+		{fieldAux3, r.Aux3},
+	} {
+		if x.value != nil {
+			reading(x.name, *x.value)
+		}
+	}
+	if r.Current != nil {
+		reading(fieldCurrent, *r.Current)
+	}
+	if r.Lux != nil {
+		reading(fieldLux, *r.Lux)
+	}
+	if r.Accel != nil {
+		reading(fieldAccel, accelJSON(*r.Accel))
+	}
+	if r.Accels != nil {
+		accels := make([]accelJSON, len(r.Accels))
+		for i, a := range r.Accels {
+			accels[i] = accelJSON(a)
+		}
+		reading(fieldAccels, accels)
+	}
+	if len(readings) > 0 {
+		v.Readings = readings
+	}
+
+	events := map[string]bool{}
+	for _, x := range []struct {
+		name  string
+		value *bool
+	}{
+		{evtButton, r.Button},
+		{evtMoving, r.Moving},
+		{evtHall, r.Hall},
+		{evtFall, r.Fall},
+		{evtPIR, r.PIR},
+		{evtIR, r.IR},
+		{evtDetect, r.Detect},
+		{evtDin, r.Din},
+		{evtDin2, r.Din2},
+		{evtFlip, r.Flip},
+	} {
+		if x.value != nil {
+			events[x.name] = *x.value
+		}
+	}
+	if len(events) > 0 {
+		v.Events = events
+	}
+
+	if b, ok := payload.UUID(); ok {
+		ib := &iBeaconJSON{}
+		if u, err := uuid.FromBytes(b); err == nil {
+			ib.UUID = strings.ToUpper(u.String())
+		}
+		ib.Major, _ = payload.Major()
+		ib.Minor, _ = payload.Minor()
+		ib.RefTx, _ = payload.RefTx()
+		v.IBeacon = ib
+	}
+	return json.Marshal(v)
+}
+
+// JSON unmarshaler of Payload, the payload is parsed from "raw" by DefaultParser,
+// other fields are informational and ignored
+func (payload *Payload) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Version int    `json:"version"`
+		Raw     string `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version != JSONVersion {
+		return fmt.Errorf("ibs: unsupported JSON schema version %d", v.Version)
+	}
+	return payload.UnmarshalText([]byte(v.Raw))
+}
+
+// Text marshaler of Payload, the payload in HEX string
+func (payload Payload) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(payload.Packet.Bytes()))), nil
+}
+
+// Text unmarshaler of Payload, input the payload in HEX string, parsed by DefaultParser
+func (payload *Payload) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("ibs: invalid payload hex string: %v", err)
+	}
+	p, _ := DefaultParser.ParseE(b)
+	*payload = *p
+	return nil
+}
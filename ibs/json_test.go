@@ -0,0 +1,176 @@
+package ibs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// Real payloads of models, same as the ones in payload_test.go
+var goldenPayloads = map[string]string{
+	"iBS02IR2_02":    "02010612FF0D0083BC4D0120AAAA05000000020A0600",
+	"iBS02M2_04":     "02010612FF0D0083BC240100AAAA37060000040B0600",
+	"iBS02PIR2_01":   "02010612FF0D0083BC4A0110AAAAFFFF000001140000",
+	"iBS03AD-A_26":   "02010612FF0D0083BC280140D809060A640026040000",
+	"iBS03AD-D_25":   "02010612FF0D0083BC280140AAAA060A640025040000",
+	"iBS03AD-NTC_23": "02010612FF0D0083BC280100AAAA060A640023040000",
+	"iBS03AD-V_24":   "02010612FF0D0083BC280100AAAA060A640024040000",
+	"iBS03F_1B":      "02010612FF0D0083BC290140AAAA020000001B090000",
+	"iBS03P_12":      "02010612FF0D0083BC2C0100BF0AD00A0000120A0600",
+	"iBS03QY_1D":     "02010612FF0D0083BC330140AAAA0A0000001D090000",
+	"iBS03Q_1C":      "02010612FF0D0083BC290140AAAA000000001C090000",
+	"iBS03RS_1A":     "02010612FF0D0083BC430100AAAA150000001A040600",
+	"iBS03R_13":      "02010612FF0D0083BC280100AAAA7200000013090000",
+	"iBS03TP_17":     "02010612FF0D0083BC280100D809060A640017040000",
+	"iBS03T_14":      "02010612FF0D0083BC3101006D0B31000000140F0600",
+	"iBS03T_15":      "02010612FF0D0083BC2801020A09FFFF000015030000",
+	"iBS04_19":       "02010612FF0D0083BC3A0101AAAAFFFF000019070000",
+	"iBS04i_18":      "02010612FF0D0083BC1F0100AAAAFFFF000018030000",
+	"iBS05CO2_34":    "02010612FF2C0883BC270100AAAA6804000034010000",
+	"iBS05G-Flip_3A": "02010612FF2C0883BC3A0101F200000000003A0A1000",
+	"iBS05G_33":      "02010612FF2C0883BC290102AAAAFFFF000033000000",
+	"iBS05H_31":      "02010612FF2C0883BC2D0100AAAA04000000310A1000",
+	"iBS05T_32":      "02010612FF2C0883BC4A0100A10AFFFF000032000000",
+	"iBS05_30":       "02010612FF2C0883BC290101AAAAFFFF000030000000",
+	"iBS06_40":       "02010612FF2C0883BC4A0100AAAAFFFF000040110000",
+	"iBS07_50":       "02010618FF2C0887BC330100110B31005A002AFF02007B0050070000",
+	"iBS08IAQ_46":    "0201061AFF2C0888BC4901000F091F025A0232004C00DE030A0046040000",
+	"iBS08T_45":      "0201061AFF2C0888BC4701010B0BA3010102000000000000000045100000",
+	"iBS09IR_47":     "0201061AFF2C0888BC390101AAAAD0000000040000000000000047080000",
+	"iBS09PIR_44":    "0201061AFF2C0888BC470110AAAAFFFF0000000000000000000044100000",
+	"iBS09PS_43":     "0201061AFF2C0888BC470120AAAA01000000000000000000000043100000",
+	"iBS09R_42":      "0201061AFF2C0888BC470100AAAA74000000000000000000000042100000",
+	"iRS02RG_22":     "02010612FF0D0083BC4D010000002400FCFE22074B58",
+}
+
+// Real payload of the model if any, or synthesized payload of the definition
+// of which fields are filled with a fixed pattern and only cover the layout
+func goldenPayload(key string, mfg, code uint16, subTypeIdx int, subtype byte) []byte {
+	if s, ok := goldenPayloads[key]; ok {
+		b, _ := hex.DecodeString(s)
+		return b
+	}
+	msd := make([]byte, subTypeIdx+3)
+	binary.LittleEndian.PutUint16(msd[0:], mfg)
+	binary.LittleEndian.PutUint16(msd[2:], code)
+	for i := 4; i < len(msd); i++ {
+		msd[i] = byte(i * 13 % 100)
+	}
+	msd[subTypeIdx] = subtype
+	return append([]byte{0x02, 0x01, 0x06, byte(len(msd) + 1), 0xFF}, msd...)
+}
+
+func TestPayload_MarshalJSON_Golden(t *testing.T) {
+	tables := []struct {
+		mfg        uint16
+		code       uint16
+		subTypeIdx int
+		defs       map[byte]payloadDef
+	}{
+		{0x000D, 0xBC83, 13, ibsCommonPayloadDefs},
+		{IngicsVendorCode, 0xBC87, 19, ibsBC87PayloadDefs},
+		{IngicsVendorCode, 0xBC88, 21, ibsBC88PayloadDefs},
+	}
+	for _, table := range tables {
+		var subtypes []int
+		for subtype := range table.defs {
+			subtypes = append(subtypes, int(subtype))
+		}
+		sort.Ints(subtypes)
+		for _, subtype := range subtypes {
+			model := table.defs[byte(subtype)].model.(string)
+			key := fmt.Sprintf("%s_%02X", model, subtype)
+			name := key + ".json"
+			t.Run(name, func(t *testing.T) {
+				p := Parse(goldenPayload(key, table.mfg, table.code, table.subTypeIdx, byte(subtype)))
+				if got, _ := p.ProductModel(); got != model {
+					t.Fatalf("ProductModel() = %v, want %v", got, model)
+				}
+				got, err := json.MarshalIndent(p, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, '\n')
+				path := filepath.Join("testdata", "golden", name)
+				if *update {
+					if err := os.WriteFile(path, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("MarshalJSON() = %s, want %s", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestPayload_JSON(t *testing.T) {
+	for _, s := range []string{
+		"02010612FF0D0083BC3101006D0B31000000140F0600",
+		"0201061AFF4C000215B9A5D27D56CC4E3AAB511F2153BCB9670000E9B2D6",
+		"02010607FF590081BC3101",
+		"",
+	} {
+		b, _ := hex.DecodeString(s)
+		data, err := json.Marshal(Parse(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p Payload
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", data, err)
+		}
+		if again, _ := json.Marshal(p); !bytes.Equal(again, data) {
+			t.Errorf("round trip = %s, want %s", again, data)
+		}
+		if p.String() != Parse(b).String() {
+			t.Errorf("String() = %v, want %v", p.String(), Parse(b).String())
+		}
+	}
+
+	b, _ := hex.DecodeString("0201061AFF4C000215B9A5D27D56CC4E3AAB511F2153BCB9670000E9B2D6")
+	data, _ := json.Marshal(Parse(b))
+	want := `"ibeacon":{"uuid":"B9A5D27D-56CC-4E3A-AB51-1F2153BCB967","major":0,"minor":59826,"ref_tx":-42}`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("MarshalJSON() = %s, want %s", data, want)
+	}
+
+	var p Payload
+	if err := json.Unmarshal([]byte(`{"version":2,"raw":"0201"}`), &p); err == nil {
+		t.Error("Unmarshal() of version 2 succeeded")
+	}
+	if err := json.Unmarshal([]byte(`{"version":1,"raw":"0X"}`), &p); err == nil {
+		t.Error("Unmarshal() of invalid hex succeeded")
+	}
+}
+
+func TestPayload_Text(t *testing.T) {
+	b, _ := hex.DecodeString("02010612FF0D0083BC3101006D0B31000000140F0600")
+	text, _ := Parse(b).MarshalText()
+	if string(text) != "02010612FF0D0083BC3101006D0B31000000140F0600" {
+		t.Errorf("MarshalText() = %s", text)
+	}
+	var p Payload
+	if err := p.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	want := "{ battery: 3.05, button: false, events: 0, humidity: 49, model: iBS03T, temperature: 29.25, userdata: 0, vendor: INGICS TECHNOLOGY CO., LTD. }"
+	if p.String() != want {
+		t.Errorf("String() = %v, want %v", p.String(), want)
+	}
+}
@@ -2,7 +2,9 @@ package ibs
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/go-ble/ble"
@@ -15,38 +17,131 @@ type Payload struct {
 	Packet adv.Packet
 	// The manufacturer specified data array
 	msdata map[string]interface{}
+	// Custom values stored by decoders, separated from the decoded readings
+	custom map[string]interface{}
+	// Name of the decoder recognized the payload
+	decoder string
+	// Why the payload is not fully decoded
+	err error
+}
+
+// Sentinel errors wrapped by ParseError, usable with errors.Is
+var (
+	ErrTruncated = errors.New("truncated manufacturer data")
+	ErrMalformed = errors.New("malformed advertising data")
+)
+
+// ParseError describes why a payload is not (fully) decoded
+type ParseError struct {
+	// Name of the failing field, e.g. "temperature" or "subtype", or "ad" for advertising data structure
+	Field string
+	// Byte offset of the failing field in manufacturer data, or in payload for "ad"
+	Offset int
+	// One of the sentinel errors above
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ibs: %v at offset %d (%v)", e.Err, e.Offset, e.Field)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
 // Parser entry
-// Input payload ([]byte) and returns the Payload instense
+// Input payload ([]byte) and returns the Payload instense, decoded by DefaultParser
 func Parse(bytes []byte) *Payload {
-	pkt := adv.NewRawPacket(bytes)
-	payload := Payload{*pkt, map[string]interface{}{}}
-	ok := payload.ibs() // call ibs parser
-	if !ok {
-		payload.apple() // call apple parser
+	return DefaultParser.Parse(bytes)
+}
+
+// Parser entry, same as Parse but returns the *ParseError if the payload is
+// malformed or not fully decoded, the partially decoded payload is returned as well
+func ParseE(bytes []byte) (*Payload, error) {
+	return DefaultParser.ParseE(bytes)
+}
+
+// Name of the decoder recognized the payload, empty if none
+func (payload Payload) Decoder() string {
+	return payload.decoder
+}
+
+// Store a custom value in the key, for Decoder implementations.
+// Custom values are read by Get only, they are kept apart from the readings
+// returned by accessors, which are stored by SetReadings and SetProduct.
+func (payload Payload) Set(key string, value interface{}) {
+	payload.custom[key] = value
+}
+
+// Record why the payload is not fully decoded, for Decoder implementations
+func (payload *Payload) SetError(err error) {
+	payload.err = err
+}
+
+// Why the payload is malformed or not fully decoded, nil if not
+func (payload Payload) Err() error {
+	return payload.err
+}
+
+// Returns true if the payload is recognized by a decoder but not fully decoded
+func (payload Payload) Partial() bool {
+	return payload.decoder != "" && payload.err != nil
+}
+
+// Returns a custom value stored by Set
+func (payload Payload) Get(key string) (value interface{}, ok bool) {
+	value, ok = payload.custom[key]
+	return
+}
+
+// Data of the first advertising data structure with the type, nil if not present.
+// Same as adv.Packet's Field method, which loops forever if the length byte is 0xFF.
+func (payload Payload) Field(typ byte) []byte {
+	b := payload.Packet.Bytes()
+	for len(b) >= 2 {
+		l := int(b[0])
+		if l < 1 || len(b) < 1+l {
+			return nil
+		}
+		if b[1] == typ {
+			return b[2 : 1+l]
+		}
+		b = b[1+l:]
 	}
-	return &payload
+	return nil
 }
 
-// Wrap to adv.Packet's ManufacturerData method
+// Same as adv.Packet's ManufacturerData method
 func (payload Payload) ManufacturerData() []byte {
-	return payload.Packet.ManufacturerData()
+	// 0xFF: manufacturer specific data
+	return payload.Field(0xFF)
 }
 
-// Wrap to adv.Packet's ServiceData method
+// Service data with 16, 32 and 128 bits UUID, same as adv.Packet's ServiceData method
+// but the entries shorter than UUID are ignored instead of panic
 func (payload Payload) ServiceData() []ble.ServiceData {
-	return payload.Packet.ServiceData()
+	var x []ble.ServiceData
+	for _, f := range [...]struct {
+		typ   byte
+		width int
+	}{{0x16, 2}, {0x20, 4}, {0x21, 16}} {
+		if b := payload.Field(f.typ); len(b) >= f.width {
+			data := make([]byte, len(b)-f.width)
+			copy(data, b[f.width:])
+			x = append(x, ble.ServiceData{UUID: ble.UUID(b[:f.width]), Data: data})
+		}
+	}
+	return x
 }
 
 // Wrap to adv.Packet's LocalName method
 func (payload Payload) LocalName() (string, bool) {
 	// 0x08: shortened local name
 	// 0x09: complete local name
-	if b := payload.Packet.Field(0x08); b != nil {
+	if b := payload.Field(0x08); b != nil {
 		return string(b), true
 	}
-	if b := payload.Packet.Field(0x09); b != nil {
+	if b := payload.Field(0x09); b != nil {
 		return string(b), true
 	}
 	return "", false
@@ -54,7 +149,7 @@ func (payload Payload) LocalName() (string, bool) {
 
 // Returns vendor code (mfg) got from manufacturer data
 func (payload Payload) VendorCode() (code uint16, ok bool) {
-	if msd := payload.ManufacturerData(); msd != nil {
+	if msd := payload.ManufacturerData(); len(msd) >= 2 {
 		return binary.LittleEndian.Uint16(msd[:2]), true
 	}
 	return 0, false
@@ -65,8 +160,8 @@ func (payload Payload) Vendor() (name string, ok bool) {
 	if code, ok := payload.VendorCode(); ok {
 		// check parsed mas first,
 		// it may contains heck for Ingics Beacon
-		if name, ok := payload.msdata["vendor"]; ok {
-			return name.(string), true
+		if name, ok := payload.msdata["vendor"].(string); ok {
+			return name, true
 		}
 		// query known vendor code if not Ingics beacon
 		if name, ok := knownVendorCode[code]; ok {
@@ -83,19 +178,22 @@ func (payload Payload) ProductModel() (name string, ok bool) {
 	if mfg, ok := payload.VendorCode(); ok {
 		msd := payload.ManufacturerData()
 		if mfg == 0x0006 { // Microsoft
+			if len(msd) < 4 {
+				return "", false
+			}
 			typ := uint8(msd[3]) & 0x3F
 			if name, ok := microsoftProductType[typ]; ok {
 				return name, true
 			}
 			return "", false
 		} else if mfg == 0x004C { // Apple
-			if msd[2] == 0x02 && len(msd) == 25 {
+			if len(msd) == 25 && msd[2] == 0x02 {
 				return "iBeacon", true
 			}
 			return "", false
 		}
-		if model, ok := payload.msdata["model"]; ok {
-			return model.(string), true
+		if model, ok := payload.msdata["model"].(string); ok {
+			return model, true
 		}
 	}
 	return "", false
@@ -103,26 +201,20 @@ func (payload Payload) ProductModel() (name string, ok bool) {
 
 // Helper function for query sensor reading
 func (payload Payload) readingInt(sensor string) (value int16, ok bool) {
-	if value, ok := payload.msdata[sensor]; ok {
-		return value.(int16), true
-	}
-	return 0, false
+	value, ok = payload.msdata[sensor].(int16)
+	return
 }
 
 // Helper function for query sensor reading
 func (payload Payload) readingUint(sensor string) (value uint16, ok bool) {
-	if value, ok := payload.msdata[sensor]; ok {
-		return value.(uint16), true
-	}
-	return 0, false
+	value, ok = payload.msdata[sensor].(uint16)
+	return
 }
 
 // Helper function for query sensor reading
 func (payload Payload) readingFloat(sensor string) (value float32, ok bool) {
-	if value, ok := payload.msdata[sensor]; ok {
-		return value.(float32), true
-	}
-	return 0, false
+	value, ok = payload.msdata[sensor].(float32)
+	return
 }
 
 // Return battery voltage (in V)
@@ -202,10 +294,8 @@ func (payload Payload) Current() (value uint, ok bool) {
 
 // Return event stat
 func (payload Payload) EventStat(evt string) (value bool, ok bool) {
-	if value, ok := payload.msdata[evt]; ok {
-		return value.(bool), true
-	}
-	return false, false
+	value, ok = payload.msdata[evt].(bool)
+	return
 }
 
 // Return if button pressed
@@ -255,16 +345,14 @@ func (payload Payload) Din2Triggered() (value bool, ok bool) {
 
 // Return accel readings
 func (payload Payload) Accel() (reading AccelReading, ok bool) {
-	if value, ok := payload.msdata[fieldAccel]; ok {
-		return value.(AccelReading), true
-	}
-	return AccelReading{0, 0, 0}, false
+	reading, ok = payload.msdata[fieldAccel].(AccelReading)
+	return
 }
 
 // Return accels readings
 func (payload Payload) Accels() (reading []AccelReading, ok bool) {
-	if value, ok := payload.msdata[fieldAccels]; ok {
-		return value.([]AccelReading), true
+	if reading, ok = payload.msdata[fieldAccels].([]AccelReading); ok {
+		return reading, true
 	}
 	return []AccelReading{}, false
 }
@@ -345,18 +433,23 @@ func (payload Payload) RefTx() (reading int, ok bool) {
 
 // return UUID of iBeacon
 func (payload Payload) UUID() (reading []byte, ok bool) {
-	if v, ok := payload.msdata["uuid"]; ok {
-		return v.([]byte), true
+	if v, ok := payload.msdata["uuid"].([]byte); ok {
+		return v, true
 	} else {
 		return []byte{}, false
 	}
 }
 
-// Stringer interface for Payload
+// Stringer interface for Payload, entries are sorted by key
 func (payload Payload) String() string {
-	var x []string
-	for k, v := range payload.msdata {
-		x = append(x, fmt.Sprintf("%v: %v", k, v))
+	keys := make([]string, 0, len(payload.msdata))
+	for k := range payload.msdata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	x := make([]string, len(keys))
+	for i, k := range keys {
+		x[i] = fmt.Sprintf("%v: %v", k, payload.msdata[k])
 	}
 	return fmt.Sprintf("{ %v }", strings.Join(x, ", "))
 }
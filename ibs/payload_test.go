@@ -2,6 +2,7 @@ package ibs
 
 import (
 	"encoding/hex"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -697,3 +698,132 @@ func TestParser_iBeacon(t *testing.T) {
 		},
 	})
 }
+
+func TestParseE(t *testing.T) {
+	cases := []struct {
+		payload string
+		model   string
+		partial bool
+		err     error
+		field   string
+		offset  int
+	}{
+		{"02010612FF0D0083BC3101006D0B31000000140F0600", "iBS03T", false, nil, "", 0},
+		// truncated before subtype
+		{"0201060CFF0D0083BC3101006D0B3100", "", true, ErrTruncated, "subtype", 13},
+		{"02010608FF590080BC310100", "", true, ErrTruncated, "subtype", 13},
+		{"02010616FF2C0888BC390101AAAAD00000000400000000000000", "", true, ErrTruncated, "subtype", 21},
+		// truncated accels of iBS01RG
+		{"02010607FF590081BC3101", "iBS01RG", true, ErrTruncated, "accels", 6},
+		// too short manufacturer data
+		{"02010602FF59", "", false, nil, "", 0},
+		{"02010604FF590080", "", false, nil, "", 0},
+		{"02010604FF060001", "", false, nil, "", 0},
+		{"02010604FF4C0002", "", false, nil, "", 0},
+		// malformed advertising data
+		{"0201061AFF4C000215", "", false, ErrMalformed, "ad", 3},
+		{"0201060216AA03FF", "", false, ErrMalformed, "ad", 6},
+	}
+	for _, c := range cases {
+		b, _ := hex.DecodeString(c.payload)
+		got, err := ParseE(b)
+		if model, _ := got.ProductModel(); model != c.model {
+			t.Errorf("ParseE(%v).ProductModel() = %q, want %q", c.payload, model, c.model)
+		}
+		if got.Err() != err || got.Partial() != c.partial {
+			t.Errorf("ParseE(%v) Err() = %v, Partial() = %v", c.payload, got.Err(), got.Partial())
+		}
+		if c.err == nil {
+			if err != nil {
+				t.Errorf("ParseE(%v) error = %v", c.payload, err)
+			}
+			continue
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || !errors.Is(err, c.err) || perr.Field != c.field || perr.Offset != c.offset {
+			t.Errorf("ParseE(%v) error = %v, want %v at %v (%v)", c.payload, err, c.err, c.offset, c.field)
+		}
+	}
+
+	// partially decoded fields are kept
+	b, _ := hex.DecodeString("02010607FF590081BC3101")
+	got := Parse(b)
+	validateFieldFunc(t, got, "BatteryVoltage", float32(3.05))
+	validateFieldFunc(t, got, "Accels", nil)
+}
+
+func TestParse_Truncated(t *testing.T) {
+	payloads := []string{
+		"02010612FF0D0083BC3101006D0B31000000140F0600",
+		"02010612FF590080BC2B0100000000000000FF000000",
+		"02010612FF0D0082BC280100AAAAFFFF000004050000",
+		"02010612FF0D0083BC4D010000002400FCFE22074B58",
+		"0201061AFF2C0888BC390101AAAAD0000000040000000000000047080000",
+		"0201061AFF4C000215B9A5D27D56CC4E3AAB511F2153BCB9670000E9B2D6",
+		"1EFF06000109200236444DA103B7448CE1A6E2220F1E9AB734C9348A35B53B",
+		"020A000816F0FF640000000012094D696E69426561636F6E5F303731343700",
+	}
+	for _, s := range payloads {
+		b, _ := hex.DecodeString(s)
+		for n := 0; n <= len(b); n++ {
+			// manufacturer data shorter than claimed by the length byte
+			p := Parse(b[:n])
+			p.ProductModel()
+			p.ServiceData()
+			p.LocalName()
+			_ = p.String()
+		}
+	}
+}
+
+func TestPayload_Readings(t *testing.T) {
+	b, _ := hex.DecodeString("02010612FF0D0083BC3101006D0B31000000140F0600")
+	r := Parse(b).Readings()
+	if r.Battery == nil || *r.Battery != 3.05 || r.Temperature == nil || *r.Temperature != 29.25 {
+		t.Errorf("Readings() = %+v", r)
+	}
+	if r.Humidity == nil || *r.Humidity != 49 || r.Button == nil || *r.Button {
+		t.Errorf("Readings() = %+v", r)
+	}
+	if r.Range != nil || r.Accel != nil || r.Accels != nil || r.PIR != nil {
+		t.Errorf("Readings() = %+v", r)
+	}
+
+	// humidity of iBS01T old firmware
+	b, _ = hex.DecodeString("02010611FF590080BC2B01006D0B31000000FF0000")
+	r = Parse(b).Readings()
+	if r.Humidity == nil || *r.Humidity != 49 || r.Temperature == nil || *r.Temperature != 29.25 {
+		t.Errorf("Readings() = %+v", r)
+	}
+
+	// merge
+	battery := float32(2.5)
+	r.Merge(Readings{Battery: &battery})
+	if *r.Battery != 2.5 || *r.Humidity != 49 {
+		t.Errorf("Merge() = %+v", r)
+	}
+}
+
+func TestPayload_SetCustom(t *testing.T) {
+	// iBS03T, custom values do not clobber the decoded readings
+	b, _ := hex.DecodeString("02010612FF0D0083BC3101006D0B31000000140F0600")
+	p := Parse(b)
+	p.Set(fieldTemperature, 23.5)
+	p.Set(fieldHumidity, int16(40))
+	p.Set("vendor", 1)
+	if v, ok := p.Temperature(); !ok || v != 29.25 {
+		t.Errorf("Temperature() = %v, %v", v, ok)
+	}
+	if v, ok := p.Humidity(); !ok || v != 49 {
+		t.Errorf("Humidity() = %v, %v", v, ok)
+	}
+	if v, _ := p.Vendor(); v != "INGICS TECHNOLOGY CO., LTD." {
+		t.Errorf("Vendor() = %v", v)
+	}
+	if v, ok := p.Get(fieldTemperature); !ok || v != 23.5 {
+		t.Errorf("Get(temperature) = %v, %v", v, ok)
+	}
+	if r := p.Readings(); r.Temperature == nil || *r.Temperature != 29.25 {
+		t.Errorf("Readings() = %+v", r)
+	}
+}
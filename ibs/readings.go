@@ -0,0 +1,387 @@
+package ibs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Sensor readings and event stats decoded from payload, nil if not present
+type Readings struct {
+	Battery        *float32
+	Temperature    *float32
+	TemperatureExt *float32
+	TemperatureEnv *float32
+	Humidity       *float32
+	Range          *int
+	GP             *float32
+	Counter        *int
+	CO2            *int
+	Voltage        *int
+	Current        *uint
+	Lux            *uint
+	PM2p5          *float32
+	PM10p0         *float32
+	VOC            *float32
+	NOx            *float32
+	Value          *int
+	UserData       *int
+	Aux1           *int
+	Aux2           *int
+	Aux3           *int
+	Accel          *AccelReading
+	Accels         []AccelReading
+	Button         *bool
+	Moving         *bool
+	Hall           *bool
+	Fall           *bool
+	PIR            *bool
+	IR             *bool
+	Detect         *bool
+	Din            *bool
+	Din2           *bool
+	Flip           *bool
+}
+
+// Returns the readings decoded from payload
+func (payload Payload) Readings() Readings {
+	var r Readings
+	float := func(dst **float32) func(float32, bool) {
+		return func(v float32, ok bool) {
+			if ok {
+				*dst = &v
+			}
+		}
+	}
+	integer := func(dst **int) func(int, bool) {
+		return func(v int, ok bool) {
+			if ok {
+				*dst = &v
+			}
+		}
+	}
+	unsigned := func(dst **uint) func(uint, bool) {
+		return func(v uint, ok bool) {
+			if ok {
+				*dst = &v
+			}
+		}
+	}
+	boolean := func(dst **bool) func(bool, bool) {
+		return func(v bool, ok bool) {
+			if ok {
+				*dst = &v
+			}
+		}
+	}
+	float(&r.Battery)(payload.BatteryVoltage())
+	float(&r.Temperature)(payload.Temperature())
+	float(&r.TemperatureExt)(payload.TemperatureExt())
+	float(&r.TemperatureEnv)(payload.TemperatureEnv())
+	float(&r.Humidity)(payload.Humidity())
+	integer(&r.Range)(payload.Range())
+	float(&r.GP)(payload.GP())
+	integer(&r.Counter)(payload.Counter())
+	integer(&r.CO2)(payload.CO2())
+	integer(&r.Voltage)(payload.Voltage())
+	unsigned(&r.Current)(payload.Current())
+	unsigned(&r.Lux)(payload.Lux())
+	float(&r.PM2p5)(payload.PM2p5())
+	float(&r.PM10p0)(payload.PM10p0())
+	float(&r.VOC)(payload.VOC())
+	float(&r.NOx)(payload.NOx())
+	integer(&r.Value)(payload.Value())
+	integer(&r.UserData)(payload.UserData())
+	integer(&r.Aux1)(payload.Aux1())
+	integer(&r.Aux2)(payload.Aux2())
+	integer(&r.Aux3)(payload.Aux3())
+	if v, ok := payload.Accel(); ok {
+		r.Accel = &v
+	}
+	if v, ok := payload.Accels(); ok {
+		r.Accels = v
+	}
+	boolean(&r.Button)(payload.ButtonPressed())
+	boolean(&r.Moving)(payload.Moving())
+	boolean(&r.Hall)(payload.HallDetected())
+	boolean(&r.Fall)(payload.Falling())
+	boolean(&r.PIR)(payload.PIRDetected())
+	boolean(&r.IR)(payload.IRDetected())
+	boolean(&r.Detect)(payload.Detected())
+	boolean(&r.Din)(payload.DinTriggered())
+	boolean(&r.Din2)(payload.Din2Triggered())
+	boolean(&r.Flip)(payload.Flip())
+	return r
+}
+
+// Update readings with the ones present in o
+func (r *Readings) Merge(o Readings) {
+	if o.Battery != nil {
+		r.Battery = o.Battery
+	}
+	if o.Temperature != nil {
+		r.Temperature = o.Temperature
+	}
+	if o.TemperatureExt != nil {
+		r.TemperatureExt = o.TemperatureExt
+	}
+	if o.TemperatureEnv != nil {
+		r.TemperatureEnv = o.TemperatureEnv
+	}
+	if o.Humidity != nil {
+		r.Humidity = o.Humidity
+	}
+	if o.Range != nil {
+		r.Range = o.Range
+	}
+	if o.GP != nil {
+		r.GP = o.GP
+	}
+	if o.Counter != nil {
+		r.Counter = o.Counter
+	}
+	if o.CO2 != nil {
+		r.CO2 = o.CO2
+	}
+	if o.Voltage != nil {
+		r.Voltage = o.Voltage
+	}
+	if o.Current != nil {
+		r.Current = o.Current
+	}
+	if o.Lux != nil {
+		r.Lux = o.Lux
+	}
+	if o.PM2p5 != nil {
+		r.PM2p5 = o.PM2p5
+	}
+	if o.PM10p0 != nil {
+		r.PM10p0 = o.PM10p0
+	}
+	if o.VOC != nil {
+		r.VOC = o.VOC
+	}
+	if o.NOx != nil {
+		r.NOx = o.NOx
+	}
+	if o.Value != nil {
+		r.Value = o.Value
+	}
+	if o.UserData != nil {
+		r.UserData = o.UserData
+	}
+	if o.Aux1 != nil {
+		r.Aux1 = o.Aux1
+	}
+	if o.Aux2 != nil {
+		r.Aux2 = o.Aux2
+	}
+	if o.Aux3 != nil {
+		r.Aux3 = o.Aux3
+	}
+	if o.Accel != nil {
+		r.Accel = o.Accel
+	}
+	if o.Accels != nil {
+		r.Accels = o.Accels
+	}
+	if o.Button != nil {
+		r.Button = o.Button
+	}
+	if o.Moving != nil {
+		r.Moving = o.Moving
+	}
+	if o.Hall != nil {
+		r.Hall = o.Hall
+	}
+	if o.Fall != nil {
+		r.Fall = o.Fall
+	}
+	if o.PIR != nil {
+		r.PIR = o.PIR
+	}
+	if o.IR != nil {
+		r.IR = o.IR
+	}
+	if o.Detect != nil {
+		r.Detect = o.Detect
+	}
+	if o.Din != nil {
+		r.Din = o.Din
+	}
+	if o.Din2 != nil {
+		r.Din2 = o.Din2
+	}
+	if o.Flip != nil {
+		r.Flip = o.Flip
+	}
+}
+
+// Returns a deep copy of readings, which shares no pointer or slice with r
+func (r Readings) Clone() Readings {
+	float := func(p **float32) {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	integer := func(p **int) {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	unsigned := func(p **uint) {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	boolean := func(p **bool) {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	x := r
+	float(&x.Battery)
+	float(&x.Temperature)
+	float(&x.TemperatureExt)
+	float(&x.TemperatureEnv)
+	float(&x.Humidity)
+	integer(&x.Range)
+	float(&x.GP)
+	integer(&x.Counter)
+	integer(&x.CO2)
+	integer(&x.Voltage)
+	unsigned(&x.Current)
+	unsigned(&x.Lux)
+	float(&x.PM2p5)
+	float(&x.PM10p0)
+	float(&x.VOC)
+	float(&x.NOx)
+	integer(&x.Value)
+	integer(&x.UserData)
+	integer(&x.Aux1)
+	integer(&x.Aux2)
+	integer(&x.Aux3)
+	if x.Accel != nil {
+		v := *x.Accel
+		x.Accel = &v
+	}
+	if x.Accels != nil {
+		x.Accels = append([]AccelReading(nil), x.Accels...)
+	}
+	boolean(&x.Button)
+	boolean(&x.Moving)
+	boolean(&x.Hall)
+	boolean(&x.Fall)
+	boolean(&x.PIR)
+	boolean(&x.IR)
+	boolean(&x.Detect)
+	boolean(&x.Din)
+	boolean(&x.Din2)
+	boolean(&x.Flip)
+	return x
+}
+
+// Error of SetReadings, usable with errors.Is
+var ErrOutOfRange = errors.New("reading out of range")
+
+// Store the readings present in r, for Decoder implementations.
+// The readings are returned by the accessors afterwards, e.g. Temperature() of r.Temperature.
+// Integer readings are stored in 16 bits as built-in decoders do, nothing is stored
+// and ErrOutOfRange is returned if any of them does not fit.
+func (payload Payload) SetReadings(r Readings) error {
+	x := map[string]interface{}{}
+	var err error
+	float := func(key string, v *float32) {
+		if v != nil {
+			x[key] = *v
+		}
+	}
+	integer := func(key string, v *int) {
+		if v == nil {
+			return
+		} else if *v < math.MinInt16 || *v > math.MaxInt16 {
+			err = fmt.Errorf("ibs: %v %d: %w", key, *v, ErrOutOfRange)
+		}
+		x[key] = int16(*v)
+	}
+	unsigned := func(key string, v *int) {
+		if v == nil {
+			return
+		} else if *v < 0 || *v > math.MaxUint16 {
+			err = fmt.Errorf("ibs: %v %d: %w", key, *v, ErrOutOfRange)
+		}
+		x[key] = uint16(*v)
+	}
+	unsignedUint := func(key string, v *uint) {
+		if v == nil {
+			return
+		} else if *v > math.MaxUint16 {
+			err = fmt.Errorf("ibs: %v %d: %w", key, *v, ErrOutOfRange)
+		}
+		x[key] = uint16(*v)
+	}
+	boolean := func(key string, v *bool) {
+		if v != nil {
+			x[key] = *v
+		}
+	}
+	float(fieldBattery, r.Battery)
+	float(fieldTemperature, r.Temperature)
+	float(fieldTempExt, r.TemperatureExt)
+	float(fieldTempEnv, r.TemperatureEnv)
+	float(fieldHumidity, r.Humidity)
+	integer(fieldRange, r.Range)
+	float(fieldGP, r.GP)
+	unsigned(fieldCounter, r.Counter)
+	unsigned(fieldCO2, r.CO2)
+	integer(fieldVoltage, r.Voltage)
+	unsignedUint(fieldCurrent, r.Current)
+	unsignedUint(fieldLux, r.Lux)
+	float(fieldPm2p5, r.PM2p5)
+	float(fieldPm10p0, r.PM10p0)
+	float(fieldVoc, r.VOC)
+	float(fieldNox, r.NOx)
+	integer(fieldValue, r.Value)
+	integer(fieldUserData, r.UserData)
+	integer(fieldAux1, r.Aux1)
+	integer(fieldAux2, r.Aux2)
+	integer(fieldAux3, r.Aux3)
+	if r.Accel != nil {
+		x[fieldAccel] = *r.Accel
+	}
+	if r.Accels != nil {
+		x[fieldAccels] = append([]AccelReading(nil), r.Accels...)
+	}
+	boolean(evtButton, r.Button)
+	boolean(evtMoving, r.Moving)
+	boolean(evtHall, r.Hall)
+	boolean(evtFall, r.Fall)
+	boolean(evtPIR, r.PIR)
+	boolean(evtIR, r.IR)
+	boolean(evtDetect, r.Detect)
+	boolean(evtDin, r.Din)
+	boolean(evtDin2, r.Din2)
+	boolean(evtFlip, r.Flip)
+	if err != nil {
+		return err
+	}
+	for k, v := range x {
+		payload.msdata[k] = v
+	}
+	return nil
+}
+
+// Store the vendor and product model names, for Decoder implementations.
+// Empty names are not stored.
+func (payload Payload) SetProduct(vendor, model string) {
+	if vendor != "" {
+		payload.msdata[fieldVendor] = vendor
+	}
+	if model != "" {
+		payload.msdata[fieldModel] = model
+	}
+}
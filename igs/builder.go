@@ -0,0 +1,78 @@
+package igs
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Builder of iGS gateway message, for simulators and tests
+type Builder struct {
+	// Message type, e.g. TypeGPRP
+	Type ReportType
+	// Beacon (Tag) BLE mac address, separators ':' and '-' are allowed
+	Beacon string
+	// Gateway (IGSXX) mac address, separators ':' and '-' are allowed
+	Gateway string
+	// RSSI value
+	RSSI int
+	// BLE payload
+	Payload []byte
+	// Optional timestamp, should not be earlier than Unix epoch
+	Timestamp *time.Time
+}
+
+// Returns the message in text line format, which round-trips through Parse
+func (b Builder) Line() (string, error) {
+	m, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+	return m.String(), nil
+}
+
+// Build the message
+func (b Builder) Build() (*Message, error) {
+	var sb strings.Builder
+	sb.WriteString("$")
+	sb.WriteString(string(b.Type))
+	sb.WriteString(",")
+	beacon, _ := NormalizeMAC(b.Beacon)
+	sb.WriteString(beacon)
+	sb.WriteString(",")
+	gateway, _ := NormalizeMAC(b.Gateway)
+	sb.WriteString(gateway)
+	sb.WriteString(",")
+	sb.WriteString(strconv.Itoa(b.RSSI))
+	sb.WriteString(",")
+	sb.WriteString(strings.ToUpper(hex.EncodeToString(b.Payload)))
+	if b.Timestamp != nil {
+		if b.Timestamp.Unix() < 0 {
+			return nil, errors.New("igs: timestamp earlier than Unix epoch")
+		}
+		sb.WriteString(",")
+		sb.WriteString(formatTimestamp(*b.Timestamp))
+	}
+	m := &Message{s: sb.String(), recv: time.Now()}
+	if err := m.scan(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Epoch time in seconds, in milliseconds precision as gateway does,
+// nanoseconds precision is used if there is sub-millisecond part
+func formatTimestamp(t time.Time) string {
+	ns := t.Nanosecond()
+	if ns%int(time.Millisecond) == 0 {
+		return strconv.FormatInt(t.Unix(), 10) + "." + pad(ns/int(time.Millisecond), 3)
+	}
+	return strconv.FormatInt(t.Unix(), 10) + "." + pad(ns, 9)
+}
+
+func pad(v int, width int) string {
+	s := strconv.Itoa(v)
+	return strings.Repeat("0", width-len(s)) + s
+}
@@ -0,0 +1,91 @@
+package igs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	payload, _ := hex.DecodeString("02010612FF0D0083BC3101006D0B31000000140F0600")
+	ts := time.Unix(1630382368, 698000000)
+	line, err := Builder{"GPRP", "d8:71:4d:78:4b:4f", "F008D1789200", -45, payload, &ts}.Line()
+	if err != nil {
+		t.Fatalf("Line() error = %v", err)
+	}
+	if line != testMessage {
+		t.Errorf("Line() = %v, want %v", line, testMessage)
+	}
+
+	_, err = Builder{"GPRP", "D8714D784B", "F008D1789200", -45, payload, nil}.Build()
+	if !errors.Is(err, ErrInvalidMAC) {
+		t.Errorf("Build() error = %v, want ErrInvalidMAC", err)
+	}
+	_, err = Builder{"", "D8714D784B4F", "F008D1789200", -45, payload, nil}.Build()
+	if !errors.Is(err, ErrMissingType) {
+		t.Errorf("Build() error = %v, want ErrMissingType", err)
+	}
+}
+
+// Generator of valid Builder for testing/quick
+func (Builder) Generate(r *rand.Rand, size int) reflect.Value {
+	types := []ReportType{TypeGPRP, TypeRSPR, TypeLRAD, TypeLRSR, Type1MAD, Type1MSR}
+	mac := func() string {
+		b := make([]byte, 6)
+		r.Read(b)
+		return hex.EncodeToString(b)
+	}
+	b := Builder{
+		Type:    types[r.Intn(len(types))],
+		Beacon:  mac(),
+		Gateway: mac(),
+		RSSI:    r.Intn(148) - 127,
+		Payload: make([]byte, r.Intn(32)),
+	}
+	r.Read(b.Payload)
+	switch r.Intn(3) {
+	case 1:
+		ts := time.Unix(r.Int63n(1<<32), int64(r.Intn(1000))*int64(time.Millisecond))
+		b.Timestamp = &ts
+	case 2:
+		ts := time.Unix(r.Int63n(1<<32), r.Int63n(int64(time.Second)))
+		b.Timestamp = &ts
+	}
+	return reflect.ValueOf(b)
+}
+
+func TestBuilder_RoundTrip(t *testing.T) {
+	f := func(b Builder) bool {
+		line, err := b.Line()
+		if err != nil {
+			t.Logf("Line() error = %v", err)
+			return false
+		}
+		m := Parse(line)
+		if m == nil || m.String() != line {
+			return false
+		}
+		payload, err := m.PayloadBytes()
+		if err != nil || !bytes.Equal(payload, b.Payload) {
+			return false
+		}
+		ts := m.Timestamp()
+		if (ts == nil) != (b.Timestamp == nil) || (ts != nil && !ts.Equal(*b.Timestamp)) {
+			return false
+		}
+		beacon, _ := NormalizeMAC(b.Beacon)
+		gateway, _ := NormalizeMAC(b.Gateway)
+		return m.ReportType() == b.Type &&
+			m.Beacon() == beacon &&
+			m.Gateway() == gateway &&
+			m.RSSI() == b.RSSI
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,53 @@
+package igs
+
+import (
+	"errors"
+	"testing"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{
+		testMessage,
+		"$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600",
+		"$LRAD,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600,2021-08-31T03:59:28.698Z",
+		"$GPRP,D8714D784B4F,F008D1789200,-45,,1630382368698",
+		"$GPRP,D8714D784B4F,F008D1789200,-45,02\n$GPRP",
+		`{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45,"payload":"0201","timestamp":1630382368.698}`,
+		`{"beacon":"D8714D784B4F","rssi":"-45"}`,
+		`{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":"-45,0201","payload":"1630382368"}`,
+		"$GPRP,D8714D784B4F",
+		"",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		m, err := ParseE(s)
+		if (m == nil) == (err == nil) {
+			t.Fatalf("ParseE() = %v, %v", m, err)
+		}
+		if err != nil {
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("ParseE() error = %v, want *ParseError", err)
+			}
+			_ = err.Error()
+			return
+		}
+		// the text line parses back into the same message
+		m2, err := ParseE(m.String())
+		if err != nil {
+			t.Fatalf("ParseE(%q) error = %v", m.String(), err)
+		}
+		if m2.MsgType() != m.MsgType() || m2.Beacon() != m.Beacon() || m2.Gateway() != m.Gateway() ||
+			m2.RSSI() != m.RSSI() || m2.Payload() != m.Payload() {
+			t.Errorf("ParseE(%q) = %v, want %v", m.String(), m2, m)
+		}
+		if ts, err := m.TimestampE(); (ts != nil) && err != nil {
+			t.Errorf("TimestampE() = %v, %v", ts, err)
+		}
+		_ = m.Time()
+		if r, err := m.Decode(); (r == nil) == (err == nil) {
+			t.Errorf("Decode() = %v, %v", r, err)
+		}
+	})
+}
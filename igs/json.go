@@ -0,0 +1,206 @@
+package igs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// JSON report format of iGS gateway, the fields are identical to the text line format
+//
+//	{
+//		"type": "GPRP",
+//		"beacon": "D8714D784B4F",
+//		"gateway": "F008D1789200",
+//		"rssi": -45,
+//		"payload": "02010612FF0D0083BC3101006D0B31000000140F0600",
+//		"timestamp": 1630382368.698
+//	}
+//
+// rssi and timestamp could be either JSON number or string, timestamp is optional.
+type jsonReport struct {
+	Type      string          `json:"type"`
+	Beacon    string          `json:"beacon"`
+	Gateway   string          `json:"gateway"`
+	RSSI      json.RawMessage `json:"rssi"`
+	Payload   string          `json:"payload"`
+	Timestamp json.RawMessage `json:"timestamp"`
+}
+
+// Error of a rejected message in batch
+type ItemError struct {
+	// Position of the message in batch
+	Index int
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("#%d: %v", e.Index, e.Err)
+}
+
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// Error returned by batch parsing, lists every rejected message
+type BatchError []ItemError
+
+func (e BatchError) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("igs: 1 message rejected: %v", e[0])
+	}
+	return fmt.Sprintf("igs: %d messages rejected, first %v", len(e), e[0])
+}
+
+// Parse JSON reports got from iGS device, either a single report object
+// or an array of report objects.
+// Rejected reports are skipped and listed in returned BatchError,
+// other errors are returned if the input is not a valid JSON document.
+func ParseJSON(data []byte) ([]*Message, error) {
+	return parseJSON(data, "")
+}
+
+// Gateway is used for the reports without gateway field
+func parseJSON(data []byte, gateway string) ([]*Message, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		m, err := parseJSONReport(data, gateway)
+		if err != nil {
+			return nil, BatchError{{0, err}}
+		}
+		return []*Message{m}, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	var msgs []*Message
+	var errs BatchError
+	for i, item := range items {
+		if m, err := parseJSONReport(item, gateway); err == nil {
+			msgs = append(msgs, m)
+		} else {
+			errs = append(errs, ItemError{i, err})
+		}
+	}
+	if len(errs) > 0 {
+		return msgs, errs
+	}
+	return msgs, nil
+}
+
+// Parse single JSON report object, convert to text line format for tokenizing
+func parseJSONReport(data []byte, gateway string) (*Message, error) {
+	var r jsonReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, &ParseError{"json", 0, string(data), ErrInvalidJSON}
+	}
+	if r.Gateway == "" {
+		r.Gateway = gateway
+	}
+	rssi, ts := jsonScalar(r.RSSI), jsonScalar(r.Timestamp)
+	// every field must be a single field of text line
+	for _, v := range []string{r.Type, r.Beacon, r.Gateway, rssi, r.Payload, ts} {
+		if strings.ContainsAny(v, ",\n") {
+			return nil, &ParseError{"json", 0, v, ErrInvalidJSON}
+		}
+	}
+	var b strings.Builder
+	b.WriteString("$")
+	b.WriteString(r.Type)
+	b.WriteString(",")
+	b.WriteString(r.Beacon)
+	b.WriteString(",")
+	b.WriteString(r.Gateway)
+	b.WriteString(",")
+	b.WriteString(rssi)
+	b.WriteString(",")
+	b.WriteString(r.Payload)
+	if ts != "" {
+		b.WriteString(",")
+		b.WriteString(ts)
+	}
+	m := &Message{s: b.String(), recv: time.Now()}
+	if err := m.scan(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Text of JSON number or string, empty for null or missing value
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	} else if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// Parse batch of messages got from iGS device, either text lines or JSON reports.
+// A body of single JSON value is a report object or an array of reports,
+// otherwise each line is parsed as Scanner does, e.g. newline-delimited JSON reports.
+// Rejected messages are skipped and listed in returned BatchError,
+// the index of text line counts non-blank lines only.
+func ParseBatch(data []byte) ([]*Message, error) {
+	return ParseBatchGateway(data, "")
+}
+
+// Same as ParseBatch, the gateway mac address is used for
+// JSON reports without gateway field, e.g. the ones got from MQTT topic
+func ParseBatchGateway(data []byte, gateway string) ([]*Message, error) {
+	return parseBatch(data, gateway, nil)
+}
+
+// Same as ParseBatchGateway, but gateway status records (e.g. heartbeats) are returned
+// apart instead of being rejected
+func ParseBatchStatus(data []byte, gateway string) ([]*Message, []*Status, error) {
+	var sts []*Status
+	msgs, err := parseBatch(data, gateway, func(st *Status) {
+		sts = append(sts, st)
+	})
+	return msgs, sts, err
+}
+
+// Status records are rejected if status is nil
+func parseBatch(data []byte, gateway string, status func(*Status)) ([]*Message, error) {
+	if t := bytes.TrimSpace(data); len(t) > 0 && (t[0] == '{' || t[0] == '[') && isJSONValue(t) {
+		return parseJSON(t, gateway)
+	}
+	// text lines or newline-delimited JSON reports
+	var msgs []*Message
+	var errs BatchError
+	sc := NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	sc.gateway = gateway
+	sc.Error = func(line int, text string, err error) {
+		errs = append(errs, ItemError{line - 1, err})
+	}
+	if status != nil {
+		sc.Status = func(line int, st *Status) {
+			status(st)
+		}
+	}
+	for sc.Scan() {
+		msgs = append(msgs, sc.Message())
+	}
+	if len(errs) > 0 {
+		return msgs, errs
+	}
+	return msgs, nil
+}
+
+// Returns true if data is exactly one JSON value
+func isJSONValue(data []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var v json.RawMessage
+	if err := dec.Decode(&v); err != nil {
+		return false
+	}
+	_, err := dec.Token()
+	return err == io.EOF
+}
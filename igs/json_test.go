@@ -0,0 +1,138 @@
+package igs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testJSONReport = `{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45,` +
+	`"payload":"02010612FF0D0083BC3101006D0B31000000140F0600","timestamp":1630382368.698}`
+
+func TestParse_JSON(t *testing.T) {
+	m := Parse(testJSONReport)
+	if m == nil {
+		t.Fatalf("Parse(%v) = nil", testJSONReport)
+	}
+	if got := m.String(); got != testMessage {
+		t.Errorf("String() = %v, want %v", got, testMessage)
+	}
+	want := Parse(testMessage)
+	if m.MsgType() != want.MsgType() || m.Beacon() != want.Beacon() || m.Gateway() != want.Gateway() ||
+		m.RSSI() != want.RSSI() || m.Payload() != want.Payload() || !m.Timestamp().Equal(*want.Timestamp()) {
+		t.Errorf("Parse(%v) = %v, want %v", testJSONReport, m, want)
+	}
+}
+
+func TestParse_JSONInjection(t *testing.T) {
+	// fields must not split into more fields of text line
+	for _, s := range []string{
+		`{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":"-45,0201","payload":"1630382368"}`,
+		`{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45,"payload":"0201","timestamp":"1,2"}`,
+	} {
+		m, err := ParseE(s)
+		if m != nil || !errors.Is(err, ErrInvalidJSON) {
+			t.Errorf("ParseE(%v) = %v, %v, want ErrInvalidJSON", s, m, err)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `[
+		{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":"-45","payload":"0201"},
+		{"type":"RSPR","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-46,"payload":"0201","timestamp":null},
+		{"type":"GPRP","beacon":"D8714D784B4F","rssi":-47,"payload":"0201"},
+		{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-48,"payload":"02,01"},
+		{"type":"LRAD","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-49,"payload":"0201","timestamp":"1630382368"}
+	]`
+	msgs, err := ParseJSON([]byte(input))
+	var types []string
+	for _, m := range msgs {
+		types = append(types, m.MsgType())
+	}
+	if got := strings.Join(types, " "); got != "GPRP RSPR LRAD" {
+		t.Errorf("ParseJSON() types = %v", got)
+	}
+	if msgs[2].Timestamp().Unix() != 1630382368 {
+		t.Errorf("ParseJSON()[2].Timestamp() = %v", msgs[2].Timestamp())
+	}
+	var errs BatchError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("ParseJSON() error = %v, want BatchError of 2 items", err)
+	}
+	if errs[0].Index != 2 || !errors.Is(errs[0], ErrInvalidMAC) {
+		t.Errorf("ParseJSON() error[0] = %v", errs[0])
+	}
+	if errs[1].Index != 3 || !errors.Is(errs[1], ErrInvalidJSON) {
+		t.Errorf("ParseJSON() error[1] = %v", errs[1])
+	}
+
+	if _, err := ParseJSON([]byte(`[{"type":`)); err == nil {
+		t.Errorf("ParseJSON() with truncated input error = nil")
+	}
+}
+
+func TestScanner_JSON(t *testing.T) {
+	input := testMessage + "\n" + testJSONReport + "\n" +
+		`[{"type":"RSPR","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45,"payload":"0201"},` +
+		`{"type":"RSPR","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45},` +
+		`{"type":"LRAD","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45,"payload":"0201"}]` + "\n" +
+		`[]` + "\n" + `{"type"` + "\n"
+	sc := NewScanner(strings.NewReader(input))
+	var types []string
+	for sc.Scan() {
+		types = append(types, sc.Message().MsgType())
+	}
+	if got := strings.Join(types, " "); got != "GPRP GPRP RSPR RSPR LRAD" {
+		t.Errorf("scanned types = %v", got)
+	}
+	if sc.Lines() != 5 || sc.Skipped() != 1 {
+		t.Errorf("Lines() = %v, Skipped() = %v, want 5, 1", sc.Lines(), sc.Skipped())
+	}
+}
+
+func TestParseBatch(t *testing.T) {
+	msgs, err := ParseBatch([]byte(testMessage + "\r\n\r\n$GPRP,D8714D784B4F\r\n" + testMessage))
+	var errs BatchError
+	if len(msgs) != 2 || !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 1 {
+		t.Errorf("ParseBatch() = %v, %v", msgs, err)
+	}
+	msgs, err = ParseBatch([]byte("\n  " + testJSONReport))
+	if len(msgs) != 1 || err != nil {
+		t.Errorf("ParseBatch() = %v, %v", msgs, err)
+	}
+	// newline-delimited JSON reports
+	msgs, err = ParseBatch([]byte(testJSONReport + "\n" + testJSONReport + "\n{\n"))
+	if len(msgs) != 2 || !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 2 {
+		t.Errorf("ParseBatch() = %v, %v", msgs, err)
+	}
+}
+
+func TestParseBatchStatus(t *testing.T) {
+	input := testMessage + "\n$HBRP,F008D1789200,1630382368\n$GPRP,D8714D784B4F\n"
+	msgs, sts, err := ParseBatchStatus([]byte(input), "")
+	var errs BatchError
+	if len(msgs) != 1 || len(sts) != 1 || sts[0].Gateway() != "F008D1789200" ||
+		!errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 2 {
+		t.Errorf("ParseBatchStatus() = %v, %v, %v", msgs, sts, err)
+	}
+	// rejected by ParseBatch
+	if msgs, err := ParseBatch([]byte(input)); len(msgs) != 1 || !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("ParseBatch() = %v, %v", msgs, err)
+	}
+}
+
+func TestParseBatchGateway(t *testing.T) {
+	input := `[{"type":"GPRP","beacon":"D8714D784B4F","rssi":-45,"payload":"0201"},` +
+		`{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789201","rssi":-45,"payload":"0201"}]`
+	msgs, err := ParseBatchGateway([]byte(input), "F008D1789200")
+	if err != nil || len(msgs) != 2 || msgs[0].Gateway() != "F008D1789200" || msgs[1].Gateway() != "F008D1789201" {
+		t.Errorf("ParseBatchGateway() = %v, %v", msgs, err)
+	}
+	input = `{"type":"GPRP","beacon":"D8714D784B4F","rssi":-45,"payload":"0201"}` + "\n" +
+		`{"type":"GPRP","beacon":"D8714D784B40","rssi":-45,"payload":"0201"}`
+	msgs, err = ParseBatchGateway([]byte(input), "F008D1789200")
+	if err != nil || len(msgs) != 2 || msgs[0].Gateway() != "F008D1789200" || msgs[1].Gateway() != "F008D1789200" {
+		t.Errorf("ParseBatchGateway() = %v, %v", msgs, err)
+	}
+}
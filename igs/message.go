@@ -1,43 +1,204 @@
 package igs
 
 import (
-	"regexp"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
 	"unsafe"
 )
 
-type Message struct{ s string }
+// Message reported by iGS gateway, fields are tokenized once while parsing
+type Message struct {
+	s       string
+	typ     string
+	beacon  string
+	gateway string
+	rssi    int
+	payload string
+	poff    int // byte offset of payload
+	ts      string
+	tsoff   int // byte offset of timestamp, 0 if not present
+	recv    time.Time
+}
+
+// Sentinel errors wrapped by ParseError, usable with errors.Is
+var (
+	ErrEmpty            = errors.New("empty message")
+	ErrMissingPrefix    = errors.New("missing '$' prefix")
+	ErrMissingType      = errors.New("missing message type")
+	ErrTruncated        = errors.New("truncated message")
+	ErrInvalidMAC       = errors.New("invalid MAC address")
+	ErrInvalidRSSI      = errors.New("invalid RSSI value")
+	ErrLineBreak        = errors.New("unexpected line break")
+	ErrInvalidPayload   = errors.New("invalid payload hex string")
+	ErrInvalidJSON      = errors.New("invalid JSON report")
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+)
+
+// ParseError describes why a gateway message was rejected
+type ParseError struct {
+	// Name of the failing field: "prefix", "type", "beacon", "gateway", "rssi", "payload", "timestamp" or "json"
+	Field string
+	// Byte offset of the failing field in the (space trimmed) message,
+	// JSON reports are converted into text line format before validation
+	Offset int
+	// The offending text
+	Text string
+	// One of the sentinel errors above
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("igs: %v at offset %d (%v): %q", e.Err, e.Offset, e.Field, e.Text)
+}
 
-const msgPattern = `^\$(.+),([0-9a-fA-F]{12}),([0-9a-fA-F]{12}),(-?\d+),(.*)$`
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
 
-// Message constructor, input message got from iGS device
+// Message constructor, input message got from iGS device,
+// either the text line or a single JSON report object
 func Parse(s string) *Message {
+	m, _ := ParseE(s)
+	return m
+}
+
+// Message constructor, same as Parse but returns a *ParseError
+// explaining why the input message was rejected
+func ParseE(s string) (*Message, error) {
+	if t := strings.TrimSpace(s); len(t) > 0 && t[0] == '{' {
+		return parseJSONReport([]byte(t), "")
+	}
 	// clone string and create message
 	b := make([]byte, len(s))
 	copy(b, s)
-	m := &Message{strings.TrimSpace(*(*string)(unsafe.Pointer(&b)))}
-	// validation
-	if m.validate() {
-		return m
+	m := &Message{s: strings.TrimSpace(*(*string)(unsafe.Pointer(&b))), recv: time.Now()}
+	// tokenize and validation
+	if err := m.scan(); err != nil {
+		return nil, err
 	}
-	return nil
+	return m, nil
 }
 
-// Stringer of Messsage
+// Stringer of Messsage, JSON reports are presented in text line format
 func (m Message) String() string {
 	return m.s
 }
 
-func (m Message) validate() bool {
-	p := regexp.MustCompile(msgPattern)
-	return p.MatchString(m.s)
+// Tokenize the message field by field, returns the first failure found
+func (m *Message) scan() error {
+	s := m.s
+	if len(s) == 0 {
+		return &ParseError{"prefix", 0, s, ErrEmpty}
+	}
+	if s[0] != '$' {
+		return &ParseError{"prefix", 0, s[:1], ErrMissingPrefix}
+	}
+	offset := 1
+	// type, beacon, gateway and rssi are terminated by comma,
+	// the rest of message is payload (and optional timestamp)
+	for _, name := range [...]string{"type", "beacon", "gateway", "rssi"} {
+		end := strings.IndexByte(s[offset:], ',')
+		if end < 0 {
+			return &ParseError{name, offset, s[offset:], ErrTruncated}
+		}
+		field := s[offset : offset+end]
+		var err error
+		switch name {
+		case "type":
+			if len(field) == 0 {
+				err = ErrMissingType
+			} else if strings.IndexByte(field, '\n') >= 0 {
+				err = ErrLineBreak
+			}
+			m.typ = field
+		case "beacon":
+			if !isMAC(field) {
+				err = ErrInvalidMAC
+			}
+			m.beacon = field
+		case "gateway":
+			if !isMAC(field) {
+				err = ErrInvalidMAC
+			}
+			m.gateway = field
+		case "rssi":
+			if !isRSSI(field) {
+				err = ErrInvalidRSSI
+			} else if rssi, e := strconv.Atoi(field); e == nil {
+				m.rssi = rssi
+			} else {
+				// out of range
+				m.rssi = -127
+			}
+		}
+		if err != nil {
+			return &ParseError{name, offset, field, err}
+		}
+		offset += end + 1
+	}
+	if strings.IndexByte(s[offset:], '\n') >= 0 {
+		return &ParseError{"payload", offset, s[offset:], ErrLineBreak}
+	}
+	// payload, followed by optional timestamp
+	m.poff = offset
+	rest := s[offset:]
+	if end := strings.IndexByte(rest, ','); end >= 0 {
+		m.payload = rest[:end]
+		rest = rest[end+1:]
+		if end := strings.IndexByte(rest, ','); end >= 0 {
+			rest = rest[:end]
+		}
+		if len(rest) > 0 {
+			m.ts = rest
+			m.tsoff = offset + end + 1
+		}
+	} else {
+		m.payload = rest
+	}
+	return nil
 }
 
-func (m Message) fields() []string {
-	p := regexp.MustCompile(msgPattern)
-	return p.FindStringSubmatch(m.s)[1:]
+// Normalize the mac address to the form in message, 12 hex digits in upper case.
+// Separators ':' and '-' are removed, returns false if the result is not a mac address.
+func NormalizeMAC(mac string) (string, bool) {
+	s := strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(mac))
+	return s, isMAC(s)
+}
+
+// MAC address in message is 12 hex digits without separator
+func isMAC(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isHexDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// RSSI is a decimal integer with optional minus sign
+func isRSSI(s string) bool {
+	if len(s) > 0 && s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // Message type
@@ -48,46 +209,67 @@ func (m Message) fields() []string {
 // 1MAD: BLE 5 1M ADV
 // 1MSR: BLE 5 1M Scan Response
 func (m Message) MsgType() string {
-	return m.fields()[0]
+	return m.typ
+}
+
+// Message type in ReportType
+func (m Message) ReportType() ReportType {
+	return ReportType(m.typ)
 }
 
 // Beacon (Tag) BLE mac address
 func (m Message) Beacon() string {
-	return m.fields()[1]
+	return m.beacon
 }
 
 // Gateway (IGSXX) mac address
 func (m Message) Gateway() string {
-	return m.fields()[2]
+	return m.gateway
 }
 
 // RSSI value
 func (m Message) RSSI() int {
-	if rssi, err := strconv.Atoi(m.fields()[3]); err == nil {
-		return rssi
-	} else {
-		return -127
-	}
+	return m.rssi
 }
 
 // BLE payload in HEX string
 func (m Message) Payload() string {
-	return strings.Split(m.fields()[4], ",")[0]
+	return m.payload
 }
 
-// Timestamp append in message, maybe nil
+// Timestamp append in message, maybe nil if not present or malformed
 func (m Message) Timestamp() *time.Time {
-	if x := strings.Split(m.fields()[4], ","); len(x) > 1 {
-		sec := 0
-		msec := 0
-		if y := strings.Split(x[1], "."); len(y) > 1 {
-			sec, _ = strconv.Atoi(y[0])
-			msec, _ = strconv.Atoi(y[1])
-		} else {
-			sec, _ = strconv.Atoi(y[0])
-		}
-		t := time.Unix(int64(sec), int64(msec*1000000))
-		return &t
+	t, _ := m.TimestampE()
+	return t
+}
+
+// Timestamp append in message, same as Timestamp but returns a *ParseError
+// if the timestamp is malformed. Returns nil without error if not present.
+func (m Message) TimestampE() (*time.Time, error) {
+	if m.tsoff == 0 {
+		return nil, nil
 	}
-	return nil
+	t, err := ParseTimestamp(m.ts)
+	if err != nil {
+		return nil, &ParseError{"timestamp", m.tsoff, m.ts, ErrInvalidTimestamp}
+	}
+	return &t, nil
+}
+
+// Timestamp append in message, or the fallback time if not present or malformed
+func (m Message) TimestampOr(fallback time.Time) time.Time {
+	if t := m.Timestamp(); t != nil {
+		return *t
+	}
+	return fallback
+}
+
+// The time message received (parsed)
+func (m Message) Received() time.Time {
+	return m.recv
+}
+
+// Timestamp append in message, or the received time if gateway sends none
+func (m Message) Time() time.Time {
+	return m.TimestampOr(m.recv)
 }
@@ -0,0 +1,164 @@
+package igs
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testMessage = "$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.698"
+
+func TestParse(t *testing.T) {
+	m := Parse(testMessage)
+	if m == nil {
+		t.Fatalf("Parse(%q) = nil", testMessage)
+	}
+	if got := m.MsgType(); got != "GPRP" {
+		t.Errorf("MsgType() = %v, want GPRP", got)
+	}
+	if got := m.Beacon(); got != "D8714D784B4F" {
+		t.Errorf("Beacon() = %v, want D8714D784B4F", got)
+	}
+	if got := m.Gateway(); got != "F008D1789200" {
+		t.Errorf("Gateway() = %v, want F008D1789200", got)
+	}
+	if got := m.RSSI(); got != -45 {
+		t.Errorf("RSSI() = %v, want -45", got)
+	}
+	if got := m.Payload(); got != "02010612FF0D0083BC3101006D0B31000000140F0600" {
+		t.Errorf("Payload() = %v", got)
+	}
+}
+
+func TestParseE(t *testing.T) {
+	cases := []struct {
+		input  string
+		field  string
+		offset int
+		text   string
+		err    error
+	}{
+		{"", "prefix", 0, "", ErrEmpty},
+		{"GPRP,D8714D784B4F,F008D1789200,-45,02", "prefix", 0, "G", ErrMissingPrefix},
+		{"$,D8714D784B4F,F008D1789200,-45,02", "type", 1, "", ErrMissingType},
+		{"$GPRP,D8714D784B4,F008D1789200,-45,02", "beacon", 6, "D8714D784B4", ErrInvalidMAC},
+		{"$GPRP,D8714D784B4F,F008D178920G,-45,02", "gateway", 19, "F008D178920G", ErrInvalidMAC},
+		{"$GPRP,D8714D784B4F,F008D1789200,-4x,02", "rssi", 32, "-4x", ErrInvalidRSSI},
+		{"$GPRP,D8714D784B4F,F008D1789200,,02", "rssi", 32, "", ErrInvalidRSSI},
+		{"$GPRP,D8714D784B4F,F008D1789200,-45", "rssi", 32, "-45", ErrTruncated},
+		{"$GPRP,D8714D784B4F", "beacon", 6, "D8714D784B4F", ErrTruncated},
+		{"$GPRP,D8714D784B4F,F008D1789200,-45,02\n$GPRP", "payload", 36, "02\n$GPRP", ErrLineBreak},
+	}
+	for _, c := range cases {
+		m, err := ParseE(c.input)
+		if m != nil {
+			t.Errorf("ParseE(%q) = %v, want nil", c.input, m)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("ParseE(%q) error = %v, want *ParseError", c.input, err)
+			continue
+		}
+		if perr.Field != c.field || perr.Offset != c.offset || perr.Text != c.text {
+			t.Errorf("ParseE(%q) error = %+v, want field %v, offset %v, text %q", c.input, perr, c.field, c.offset, c.text)
+		}
+		if !errors.Is(err, c.err) {
+			t.Errorf("ParseE(%q) error = %v, want %v", c.input, err, c.err)
+		}
+	}
+}
+
+// pattern used by the regexp based implementation, kept for comparison
+const legacyPattern = `^\$(.+),([0-9a-fA-F]{12}),([0-9a-fA-F]{12}),(-?\d+),(.*)$`
+
+func TestParse_LegacyCompatible(t *testing.T) {
+	p := regexp.MustCompile(legacyPattern)
+	inputs := []string{
+		testMessage,
+		"$RSPR,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600",
+		"$GPRP,d8714d784b4f,f008d1789200,0,",
+		"$LRAD,D8714D784B4F,F008D1789200,45,0201,1630382368,extra",
+		"  $GPRP,D8714D784B4F,F008D1789200,-45,0201  ",
+	}
+	for _, input := range inputs {
+		m := Parse(input)
+		if m == nil {
+			t.Errorf("Parse(%q) = nil", input)
+			continue
+		}
+		want := p.FindStringSubmatch(strings.TrimSpace(input))[1:]
+		got := []string{m.MsgType(), m.Beacon(), m.Gateway(), strconv.Itoa(m.RSSI()), m.Payload()}
+		if want[4] = strings.Split(want[4], ",")[0]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := Parse(testMessage)
+		_, _, _, _, _, _ = m.MsgType(), m.Beacon(), m.Gateway(), m.RSSI(), m.Payload(), m.Timestamp()
+	}
+}
+
+// regexp based implementation, which re-matches the message in every accessor
+func BenchmarkParse_Regexp(b *testing.B) {
+	b.ReportAllocs()
+	fields := func(s string) []string {
+		return regexp.MustCompile(legacyPattern).FindStringSubmatch(s)[1:]
+	}
+	for i := 0; i < b.N; i++ {
+		s := strings.TrimSpace(testMessage)
+		if regexp.MustCompile(legacyPattern).MatchString(s) {
+			_, _, _ = fields(s)[0], fields(s)[1], fields(s)[2]
+			_, _ = strconv.Atoi(fields(s)[3])
+			_ = strings.Split(fields(s)[4], ",")[0]
+			_ = strings.Split(fields(s)[4], ",")
+		}
+	}
+}
+
+func TestMessage_Decode(t *testing.T) {
+	r, err := Parse(testMessage).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if r.Type != "GPRP" || r.Beacon != "D8714D784B4F" || r.Gateway != "F008D1789200" || r.RSSI != -45 {
+		t.Errorf("Decode() = %+v", r)
+	}
+	if r.Timestamp == nil || r.Timestamp.Unix() != 1630382368 {
+		t.Errorf("Decode().Timestamp = %v", r.Timestamp)
+	}
+	if model, ok := r.Payload.ProductModel(); !ok || model != "iBS03T" {
+		t.Errorf("Decode().Payload.ProductModel() = %v, %v", model, ok)
+	}
+
+	_, err = Parse("$GPRP,D8714D784B4F,F008D1789200,-45,02010X").Decode()
+	var perr *ParseError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrInvalidPayload) || perr.Offset != 36 {
+		t.Errorf("Decode() error = %v, want ErrInvalidPayload at offset 36", err)
+	}
+}
+
+func TestNormalizeMAC(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"F008D1789200", "F008D1789200", true},
+		{"f0:08:d1:78:92:00", "F008D1789200", true},
+		{"F0-08-D1-78-92-00", "F008D1789200", true},
+		{"F008D17892", "F008D17892", false},
+		{"G008D1789200", "G008D1789200", false},
+	}
+	for _, c := range cases {
+		if got, ok := NormalizeMAC(c.in); got != c.want || ok != c.ok {
+			t.Errorf("NormalizeMAC(%v) = %v, %v, want %v, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
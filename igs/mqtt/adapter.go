@@ -0,0 +1,153 @@
+// Package mqtt feeds messages published by iGS gateways to MQTT broker into igs/ibs parsers.
+//
+// The package does not depend on any MQTT library, wrap the client of
+// the library in use (e.g. eclipse/paho.mqtt.golang) to implement Client.
+package mqtt
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Callback of messages published to subscribed topic
+type MessageCallback func(topic string, payload []byte)
+
+// MQTT client used by Adapter
+type Client interface {
+	// Connect to the broker
+	Connect(ctx context.Context) error
+	// Subscribe the topic filter, callback could be called concurrently
+	Subscribe(ctx context.Context, filter string, qos byte, callback MessageCallback) error
+	// Returns a channel closed when the current connection is lost
+	Done() <-chan struct{}
+	// Disconnect from the broker
+	Disconnect()
+}
+
+// Handler of decoded reports
+type Handler func(ctx context.Context, topic string, r *igs.Report)
+
+const (
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = time.Minute
+)
+
+// Adapter subscribes topics of iGS gateways and delivers decoded reports to handler
+type Adapter struct {
+	// MQTT client
+	Client Client
+	// Topic filters to subscribe, e.g. "igs/+/report"
+	Topics []string
+	// QoS of subscriptions
+	QoS byte
+	// Handler of decoded reports
+	Handler Handler
+	// Extract the gateway mac address from topic, for the reports without gateway field.
+	// GatewayFromTopic is used if nil
+	Gateway func(topic string) string
+	// Called for connection failures (with empty topic) and rejected messages, if not nil
+	Error func(topic string, err error)
+	// Delay before reconnecting, doubled for each failure. 1 second if zero
+	ReconnectDelay time.Duration
+	// Max delay before reconnecting, 1 minute if zero
+	MaxReconnectDelay time.Duration
+}
+
+// Connect, subscribe and deliver reports until ctx is done.
+// Reconnects and resubscribes when the connection is lost.
+func (a *Adapter) Run(ctx context.Context) error {
+	delay := a.ReconnectDelay
+	if delay <= 0 {
+		delay = defaultReconnectDelay
+	}
+	max := a.MaxReconnectDelay
+	if max <= 0 {
+		max = defaultMaxReconnectDelay
+	}
+	backoff := delay
+	for {
+		if err := a.session(ctx); err != nil {
+			a.report("", err)
+		} else {
+			backoff = delay
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		if backoff *= 2; backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// Connect and subscribe, blocks until connection lost or ctx is done
+func (a *Adapter) session(ctx context.Context) error {
+	if err := a.Client.Connect(ctx); err != nil {
+		return err
+	}
+	defer a.Client.Disconnect()
+	for _, filter := range a.Topics {
+		if err := a.Client.Subscribe(ctx, filter, a.QoS, func(topic string, payload []byte) {
+			a.handle(ctx, topic, payload)
+		}); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-a.Client.Done():
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (a *Adapter) handle(ctx context.Context, topic string, payload []byte) {
+	gateway := a.Gateway
+	if gateway == nil {
+		gateway = GatewayFromTopic
+	}
+	msgs, err := igs.ParseBatchGateway(payload, gateway(topic))
+	if errs, ok := err.(igs.BatchError); ok {
+		for _, e := range errs {
+			a.report(topic, e)
+		}
+	} else if err != nil {
+		a.report(topic, err)
+	}
+	for _, m := range msgs {
+		r, err := m.Decode()
+		if err != nil {
+			a.report(topic, err)
+			continue
+		}
+		if a.Handler != nil {
+			a.Handler(ctx, topic, r)
+		}
+	}
+}
+
+func (a *Adapter) report(topic string, err error) {
+	if a.Error != nil {
+		a.Error(topic, err)
+	}
+}
+
+// Returns the last topic level which is a mac address (12 hex digits,
+// with or without ':' or '-' separators) normalized by igs.NormalizeMAC,
+// or empty string if not found
+func GatewayFromTopic(topic string) string {
+	levels := strings.Split(topic, "/")
+	for i := len(levels) - 1; i >= 0; i-- {
+		if mac, ok := igs.NormalizeMAC(levels[i]); ok {
+			return mac
+		}
+	}
+	return ""
+}
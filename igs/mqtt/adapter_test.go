@@ -0,0 +1,206 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// In-process MQTT broker stand-in
+type broker struct {
+	mu       sync.Mutex
+	clients  map[*client]bool
+	failures int // number of following connections to be refused
+}
+
+type subscription struct {
+	filter   string
+	callback MessageCallback
+}
+
+type client struct {
+	b    *broker
+	done chan struct{}
+	subs []subscription
+}
+
+func (c *client) Connect(ctx context.Context) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	if c.b.failures > 0 {
+		c.b.failures--
+		return errors.New("connection refused")
+	}
+	c.done = make(chan struct{})
+	c.subs = nil
+	c.b.clients[c] = true
+	return nil
+}
+
+func (c *client) Subscribe(ctx context.Context, filter string, qos byte, callback MessageCallback) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	c.subs = append(c.subs, subscription{filter, callback})
+	return nil
+}
+
+func (c *client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *client) Disconnect() {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	c.b.disconnect(c)
+}
+
+func (b *broker) disconnect(c *client) {
+	if b.clients[c] {
+		delete(b.clients, c)
+		close(c.done)
+	}
+}
+
+// Drop all connections
+func (b *broker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		b.disconnect(c)
+	}
+}
+
+func (b *broker) subscriptions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for c := range b.clients {
+		n += len(c.subs)
+	}
+	return n
+}
+
+func (b *broker) publish(topic string, payload []byte) {
+	b.mu.Lock()
+	var callbacks []MessageCallback
+	for c := range b.clients {
+		for _, sub := range c.subs {
+			if match(sub.filter, topic) {
+				callbacks = append(callbacks, sub.callback)
+			}
+		}
+	}
+	b.mu.Unlock()
+	for _, cb := range callbacks {
+		cb(topic, payload)
+	}
+}
+
+// MQTT topic filter matching with '+' and '#' wildcards
+func match(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) || (level != "+" && level != t[i]) {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	b := &broker{clients: map[*client]bool{}, failures: 2}
+	var mu sync.Mutex
+	var reports []*igs.Report
+	var errs []error
+	a := &Adapter{
+		Client: &client{b: b},
+		Topics: []string{"igs/+/report", "other/#"},
+		Handler: func(ctx context.Context, topic string, r *igs.Report) {
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, r)
+		},
+		Error: func(topic string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+		ReconnectDelay: time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+
+	waitFor(t, func() bool { return b.subscriptions() == 2 })
+	b.publish("igs/F008D1789200/report",
+		[]byte("$GPRP,D8714D784B4F,F008D1789201,-45,02010612FF0D0083BC3101006D0B31000000140F0600"))
+	b.publish("igs/F0:08:D1:78:92:00/report",
+		[]byte(`{"type":"RSPR","beacon":"D8714D784B4F","rssi":-46,"payload":"0201"}`))
+	b.publish("unsubscribed/F008D1789200", []byte("$GPRP,D8714D784B4F,F008D1789200,-45,0201"))
+
+	// reconnect and resubscribe
+	b.drop()
+	waitFor(t, func() bool { return b.subscriptions() == 2 })
+	b.publish("other/gw/F008D1789202", []byte(`[{"type":"GPRP","beacon":"D8714D784B4F","rssi":-47,"payload":"0201"}]`))
+	b.publish("other/gw/F008D1789202", []byte("$GPRP,invalid"))
+	b.publish("other/gw/F008D1789202", []byte("$GPRP,D8714D784B4F,F008D1789200,-45,02X1"))
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if b.subscriptions() != 0 {
+		t.Errorf("not disconnected")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var got []string
+	for _, r := range reports {
+		got = append(got, string(r.Type)+"@"+r.Gateway)
+	}
+	if strings.Join(got, " ") != "GPRP@F008D1789201 RSPR@F008D1789200 GPRP@F008D1789202" {
+		t.Errorf("reports = %v", got)
+	}
+	if model, _ := reports[0].Payload.ProductModel(); model != "iBS03T" {
+		t.Errorf("reports[0].Payload.ProductModel() = %v", model)
+	}
+	// 2 connection failures, 2 rejected messages
+	if len(errs) != 4 || !errors.Is(errs[2], igs.ErrTruncated) || !errors.Is(errs[3], igs.ErrInvalidPayload) {
+		t.Errorf("errors = %v", errs)
+	}
+}
+
+func TestGatewayFromTopic(t *testing.T) {
+	cases := map[string]string{
+		"igs/F008D1789200/report":       "F008D1789200",
+		"igs/f0:08:d1:78:92:00":         "F008D1789200",
+		"igs/F0-08-D1-78-92-00/report":  "F008D1789200",
+		"igs/F008D1789200/F008D1789201": "F008D1789201",
+		"igs/gateway/report":            "",
+	}
+	for topic, want := range cases {
+		if got := GatewayFromTopic(topic); got != want {
+			t.Errorf("GatewayFromTopic(%v) = %v, want %v", topic, got, want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package igs
+
+import (
+	"strings"
+	"time"
+)
+
+// Kind of line got from iGS gateway
+type Kind int
+
+const (
+	KindUnknown Kind = iota // not recognized, raw text is preserved
+	KindReport              // advertisement report, see Message
+	KindStatus              // gateway status or heartbeat, see Status
+)
+
+// Stringer of Kind
+func (k Kind) String() string {
+	switch k {
+	case KindReport:
+		return "Report"
+	case KindStatus:
+		return "Status"
+	}
+	return "Unknown"
+}
+
+// Gateway status or heartbeat record, which is a line in form of
+//
+//	$TYPE,<gateway mac>[,field...]
+//
+// TYPE is anything except the advertisement report types
+type Status struct {
+	s       string
+	typ     string
+	gateway string
+	fields  []string
+	recv    time.Time
+}
+
+// Stringer of Status
+func (st Status) String() string {
+	return st.s
+}
+
+// Status record type
+func (st Status) Type() string {
+	return st.typ
+}
+
+// Gateway (IGSXX) mac address
+func (st Status) Gateway() string {
+	return st.gateway
+}
+
+// Fields following the gateway mac address
+func (st Status) Fields() []string {
+	return st.fields
+}
+
+// The time record received (parsed)
+func (st Status) Received() time.Time {
+	return st.recv
+}
+
+// Whether the record is a heartbeat (HBRP)
+func (st Status) Heartbeat() bool {
+	return st.typ == "HBRP"
+}
+
+// Timestamp of heartbeat, which is the first field following the gateway mac address.
+// Returns nil if not a heartbeat, not present or malformed.
+func (st Status) Timestamp() *time.Time {
+	t, _ := st.TimestampE()
+	return t
+}
+
+// Timestamp of heartbeat, same as Timestamp but returns a *ParseError
+// if the timestamp is malformed. Returns nil without error if not a heartbeat or not present.
+func (st Status) TimestampE() (*time.Time, error) {
+	if !st.Heartbeat() || len(st.fields) == 0 || len(st.fields[0]) == 0 {
+		return nil, nil
+	}
+	t, err := ParseTimestamp(st.fields[0])
+	if err != nil {
+		// $TYPE,<gateway>,
+		off := len(st.typ) + len(st.gateway) + 3
+		return nil, &ParseError{"timestamp", off, st.fields[0], ErrInvalidTimestamp}
+	}
+	return &t, nil
+}
+
+// Timestamp of heartbeat, or the received time if not present or malformed
+func (st Status) Time() time.Time {
+	if t := st.Timestamp(); t != nil {
+		return *t
+	}
+	return st.recv
+}
+
+// Classified line got from iGS gateway
+type Record struct {
+	Kind Kind
+	// The space trimmed line
+	Raw string
+	// Parsed advertisement report, for KindReport
+	Message *Message
+	// Parsed status record, for KindStatus
+	Status *Status
+	// Why the line is not an advertisement report, for KindStatus and KindUnknown
+	Err error
+}
+
+// Classify the line got from iGS gateway, which never fails,
+// lines neither a report nor a status record are KindUnknown
+func Classify(s string) *Record {
+	m, err := ParseE(s)
+	if err == nil {
+		return &Record{Kind: KindReport, Raw: m.String(), Message: m}
+	}
+	raw := strings.TrimSpace(s)
+	if st := parseStatus(raw); st != nil {
+		return &Record{Kind: KindStatus, Raw: raw, Status: st, Err: err}
+	}
+	return &Record{Kind: KindUnknown, Raw: raw, Err: err}
+}
+
+func parseStatus(s string) *Status {
+	if len(s) == 0 || s[0] != '$' || strings.IndexByte(s, '\n') >= 0 {
+		return nil
+	}
+	fields := strings.Split(s[1:], ",")
+	typ := ReportType(fields[0])
+	if len(fields) < 2 || len(typ) == 0 || typ.Known() || !isMAC(fields[1]) {
+		return nil
+	}
+	return &Status{
+		s:       s,
+		typ:     fields[0],
+		gateway: fields[1],
+		fields:  fields[2:],
+		recv:    time.Now(),
+	}
+}
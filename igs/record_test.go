@@ -0,0 +1,82 @@
+package igs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		input string
+		kind  Kind
+	}{
+		{testMessage, KindReport},
+		{"$XXXX,D8714D784B4F,F008D1789200,-45,0201", KindReport},
+		{"$HBRP,F008D1789200,1630382368", KindStatus},
+		{"$STAT,F008D1789200", KindStatus},
+		{"$GPRP,D8714D784B4F", KindUnknown},
+		{"$HBRP,F008D17892", KindUnknown},
+		{"hello", KindUnknown},
+		{"", KindUnknown},
+	}
+	for _, c := range cases {
+		r := Classify(c.input)
+		if r.Kind != c.kind {
+			t.Errorf("Classify(%q).Kind = %v, want %v", c.input, r.Kind, c.kind)
+		}
+		if r.Raw != strings.TrimSpace(c.input) {
+			t.Errorf("Classify(%q).Raw = %q", c.input, r.Raw)
+		}
+		if (r.Kind == KindReport) != (r.Err == nil) {
+			t.Errorf("Classify(%q).Err = %v", c.input, r.Err)
+		}
+	}
+
+	st := Classify("$HBRP,F008D1789200,1630382368,ok").Status
+	if st.Type() != "HBRP" || st.Gateway() != "F008D1789200" || !reflect.DeepEqual(st.Fields(), []string{"1630382368", "ok"}) {
+		t.Errorf("Status = %v %v %v", st.Type(), st.Gateway(), st.Fields())
+	}
+	if r := Classify("$GPRP,D8714D784B4F"); !errors.Is(r.Err, ErrTruncated) {
+		t.Errorf("Classify().Err = %v, want ErrTruncated", r.Err)
+	}
+}
+
+func TestScanner_Status(t *testing.T) {
+	input := testMessage + "\n$HBRP,F008D1789200,1630382368\n$GPRP,D8714D784B4F\n"
+	sc := NewScanner(strings.NewReader(input))
+	var status []string
+	sc.Status = func(line int, st *Status) {
+		status = append(status, st.Type())
+	}
+	n := 0
+	for sc.Scan() {
+		n++
+	}
+	if n != 1 || len(status) != 1 || status[0] != "HBRP" || sc.Skipped() != 1 {
+		t.Errorf("scanned %v messages, status %v, skipped %v", n, status, sc.Skipped())
+	}
+}
+
+func TestStatus_Timestamp(t *testing.T) {
+	st := Classify("$HBRP,F008D1789200,1630382368.698").Status
+	if !st.Heartbeat() || st.Timestamp() == nil || !st.Timestamp().Equal(time.UnixMilli(1630382368698)) || !st.Time().Equal(*st.Timestamp()) {
+		t.Errorf("Timestamp() = %v", st.Timestamp())
+	}
+	st = Classify("$HBRP,F008D1789200").Status
+	if ts, err := st.TimestampE(); ts != nil || err != nil || !st.Time().Equal(st.Received()) {
+		t.Errorf("TimestampE() = %v, %v, want nil", ts, err)
+	}
+	var perr *ParseError
+	st = Classify("$HBRP,F008D1789200,yesterday").Status
+	if ts, err := st.TimestampE(); ts != nil || !errors.As(err, &perr) || !errors.Is(err, ErrInvalidTimestamp) || perr.Offset != 19 {
+		t.Errorf("TimestampE() = %v, %v, want ErrInvalidTimestamp at offset 19", ts, err)
+	}
+	// fields of other records are not known
+	st = Classify("$STAT,F008D1789200,1630382368").Status
+	if st.Heartbeat() || st.Timestamp() != nil {
+		t.Errorf("Timestamp() = %v, want nil", st.Timestamp())
+	}
+}
@@ -0,0 +1,52 @@
+package igs
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/ibs"
+)
+
+// Decoded gateway message, combines the message fields and the parsed BLE payload
+type Report struct {
+	// Message type
+	Type ReportType
+	// Beacon (Tag) BLE mac address
+	Beacon string
+	// Gateway (IGSXX) mac address
+	Gateway string
+	// RSSI value
+	RSSI int
+	// Timestamp append in message, maybe nil
+	Timestamp *time.Time
+	// The time message received
+	Received time.Time
+	// Parsed BLE payload
+	Payload *ibs.Payload
+}
+
+// BLE payload in bytes
+func (m Message) PayloadBytes() ([]byte, error) {
+	b, err := hex.DecodeString(m.payload)
+	if err != nil {
+		return nil, &ParseError{"payload", m.poff, m.payload, ErrInvalidPayload}
+	}
+	return b, nil
+}
+
+// Decode the message and its BLE payload into a Report
+func (m Message) Decode() (*Report, error) {
+	b, err := m.PayloadBytes()
+	if err != nil {
+		return nil, err
+	}
+	return &Report{
+		Type:      m.ReportType(),
+		Beacon:    m.Beacon(),
+		Gateway:   m.Gateway(),
+		RSSI:      m.RSSI(),
+		Timestamp: m.Timestamp(),
+		Received:  m.Received(),
+		Payload:   ibs.Parse(b),
+	}, nil
+}
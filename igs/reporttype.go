@@ -0,0 +1,72 @@
+package igs
+
+// Message type reported by iGS gateway
+type ReportType string
+
+const (
+	TypeGPRP ReportType = "GPRP" // BLE4.2 General Purpose Report
+	TypeRSPR ReportType = "RSPR" // BLE4.2 Scan Response Report
+	TypeLRAD ReportType = "LRAD" // BLE 5 Long Range ADV
+	TypeLRSR ReportType = "LRSR" // BLE 5 Long Range Scan Response
+	Type1MAD ReportType = "1MAD" // BLE 5 1M ADV
+	Type1MSR ReportType = "1MSR" // BLE 5 1M Scan Response
+)
+
+// BLE physical layer the report received from
+type PHY int
+
+const (
+	PHYUnknown PHY = iota
+	PHY1M          // LE 1M
+	PHYCoded       // LE Coded (long range)
+)
+
+// Stringer of PHY
+func (p PHY) String() string {
+	switch p {
+	case PHY1M:
+		return "1M"
+	case PHYCoded:
+		return "Coded"
+	}
+	return "Unknown"
+}
+
+// Stringer of ReportType
+func (t ReportType) String() string {
+	return string(t)
+}
+
+// Returns if the report type is one of the known types
+func (t ReportType) Known() bool {
+	switch t {
+	case TypeGPRP, TypeRSPR, TypeLRAD, TypeLRSR, Type1MAD, Type1MSR:
+		return true
+	}
+	return false
+}
+
+// Returns if the report is a scan response
+func (t ReportType) IsScanResponse() bool {
+	return t == TypeRSPR || t == TypeLRSR || t == Type1MSR
+}
+
+// Returns the PHY the report received from
+func (t ReportType) PHY() PHY {
+	switch t {
+	case TypeGPRP, TypeRSPR, Type1MAD, Type1MSR:
+		return PHY1M
+	case TypeLRAD, TypeLRSR:
+		return PHYCoded
+	}
+	return PHYUnknown
+}
+
+// Returns if the report is received by BLE 5 extended scanning
+func (t ReportType) IsBLE5() bool {
+	switch t {
+	case TypeLRAD, TypeLRSR, Type1MAD, Type1MSR:
+		return true
+	}
+	return false
+}
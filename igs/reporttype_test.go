@@ -0,0 +1,38 @@
+package igs
+
+import "testing"
+
+func TestReportType(t *testing.T) {
+	cases := []struct {
+		typ          ReportType
+		known        bool
+		scanResponse bool
+		phy          PHY
+		ble5         bool
+	}{
+		{TypeGPRP, true, false, PHY1M, false},
+		{TypeRSPR, true, true, PHY1M, false},
+		{TypeLRAD, true, false, PHYCoded, true},
+		{TypeLRSR, true, true, PHYCoded, true},
+		{Type1MAD, true, false, PHY1M, true},
+		{Type1MSR, true, true, PHY1M, true},
+		{"XXXX", false, false, PHYUnknown, false},
+	}
+	for _, c := range cases {
+		if got := c.typ.Known(); got != c.known {
+			t.Errorf("%v.Known() = %v, want %v", c.typ, got, c.known)
+		}
+		if got := c.typ.IsScanResponse(); got != c.scanResponse {
+			t.Errorf("%v.IsScanResponse() = %v, want %v", c.typ, got, c.scanResponse)
+		}
+		if got := c.typ.PHY(); got != c.phy {
+			t.Errorf("%v.PHY() = %v, want %v", c.typ, got, c.phy)
+		}
+		if got := c.typ.IsBLE5(); got != c.ble5 {
+			t.Errorf("%v.IsBLE5() = %v, want %v", c.typ, got, c.ble5)
+		}
+	}
+	if got := Parse("$LRSR,D8714D784B4F,F008D1789200,-45,0201").ReportType(); got != TypeLRSR {
+		t.Errorf("ReportType() = %v, want LRSR", got)
+	}
+}
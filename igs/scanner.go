@@ -0,0 +1,143 @@
+package igs
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Scanner reads gateway messages line by line from an io.Reader,
+// like the stream got from iGS gateway in TCP, UDP or serial mode.
+// Each line is either a text message, a JSON report object or a JSON array of reports.
+type Scanner struct {
+	sc      *bufio.Scanner
+	msg     *Message
+	pending []*Message // rest of messages in JSON array
+	lines   int
+	skipped int
+	gateway string // for JSON reports without gateway field
+	// Error is called for each line failed to be parsed, if not nil.
+	// The scanning continues after the line is skipped
+	Error func(line int, text string, err error)
+	// Status is called for each gateway status record, if not nil.
+	// Status records are handled as failed lines if Status is nil
+	Status func(line int, st *Status)
+}
+
+// Scanner constructor
+func NewScanner(r io.Reader) *Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Split(scanLines)
+	return &Scanner{sc: sc}
+}
+
+// Set the buffer used while scanning, see bufio.Scanner.Buffer
+func (s *Scanner) Buffer(buf []byte, max int) {
+	s.sc.Buffer(buf, max)
+}
+
+// Advances to the next valid message, which will be available through Message.
+// Blank lines are ignored, invalid lines are reported to Error and skipped.
+// Returns false when the scan stops by reaching the end of input or an I/O error.
+func (s *Scanner) Scan() bool {
+	if len(s.pending) > 0 {
+		s.msg, s.pending = s.pending[0], s.pending[1:]
+		return true
+	}
+	for s.sc.Scan() {
+		text := s.sc.Text()
+		line := bytes.TrimSpace(s.sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		s.lines++
+		if line[0] == '[' {
+			if s.scanArray(line, text) {
+				return true
+			}
+			continue
+		}
+		var m *Message
+		var err error
+		if line[0] == '{' {
+			m, err = parseJSONReport(line, s.gateway)
+		} else {
+			m, err = ParseE(text)
+		}
+		if err != nil {
+			if st := parseStatus(string(line)); st != nil && s.Status != nil {
+				s.Status(s.lines, st)
+			} else {
+				s.skip(text, err)
+			}
+			continue
+		}
+		s.msg = m
+		return true
+	}
+	s.msg = nil
+	return false
+}
+
+// Handle the line of JSON array, rejected reports are reported individually
+func (s *Scanner) scanArray(line []byte, text string) bool {
+	msgs, err := parseJSON(line, s.gateway)
+	if errs, ok := err.(BatchError); ok {
+		for _, e := range errs {
+			s.skip(text, e)
+		}
+	} else if err != nil {
+		s.skip(text, err)
+	}
+	if len(msgs) == 0 {
+		return false
+	}
+	s.msg, s.pending = msgs[0], msgs[1:]
+	return true
+}
+
+func (s *Scanner) skip(text string, err error) {
+	s.skipped++
+	if s.Error != nil {
+		s.Error(s.lines, text, err)
+	}
+}
+
+// The most recent message got by Scan
+func (s *Scanner) Message() *Message {
+	return s.msg
+}
+
+// The first non-EOF I/O error encountered by the Scanner
+func (s *Scanner) Err() error {
+	return s.sc.Err()
+}
+
+// Number of non-blank lines read
+func (s *Scanner) Lines() int {
+	return s.lines
+}
+
+// Number of messages skipped due to parsing failure
+func (s *Scanner) Skipped() int {
+	return s.skipped
+}
+
+// Split function for bufio.Scanner,
+// lines are terminated by any of CR, LF or CRLF
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	// request more data
+	return 0, nil, nil
+}
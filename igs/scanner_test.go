@@ -0,0 +1,66 @@
+package igs
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScanner(t *testing.T) {
+	input := strings.Join([]string{
+		testMessage,
+		"",
+		"$RSPR,D8714D784B4F,F008D1789200,-47,0201",
+		"$GPRP,D8714D784B4F,F008D1789200,-4x,0201",
+		"   ",
+		"$LRAD,D8714D784B4F,F008D1789200,-50,0201\r",
+		"GPRP,D8714D784B4F",
+		"$1MAD,D8714D784B4F,F008D1789200,-51,0201",
+	}, "\n")
+	// deliver the input one byte per read, lines are split across reads
+	sc := NewScanner(iotest.OneByteReader(strings.NewReader(input)))
+	var errLines []int
+	sc.Error = func(line int, text string, err error) {
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("line %v: error = %v, want *ParseError", line, err)
+		}
+		errLines = append(errLines, line)
+	}
+	var types []string
+	for sc.Scan() {
+		types = append(types, sc.Message().MsgType())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if got := strings.Join(types, " "); got != "GPRP RSPR LRAD 1MAD" {
+		t.Errorf("scanned types = %v", got)
+	}
+	if sc.Lines() != 6 || sc.Skipped() != 2 {
+		t.Errorf("Lines() = %v, Skipped() = %v, want 6, 2", sc.Lines(), sc.Skipped())
+	}
+	if len(errLines) != 2 || errLines[0] != 3 || errLines[1] != 5 {
+		t.Errorf("error lines = %v, want [3 5]", errLines)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestScanner_ReadError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader(testMessage+"\r\n"), errReader{io.ErrUnexpectedEOF})
+	sc := NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+	}
+	if n != 1 || sc.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("scanned %v messages, Err() = %v", n, sc.Err())
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+const defaultMaxBodySize = 10 << 20
+
+// Handler of a batch of messages received in single request,
+// returning error fails the request with status 500
+type BatchHandler func(ctx context.Context, msgs []*igs.Message, src SourceAddr) error
+
+// HTTP handler receives messages from iGS gateways in HTTP client mode.
+// The request body is either text lines or JSON reports, optionally gzip encoded.
+//
+// Responses with status 200 if all messages accepted, 207 if some rejected,
+// 400 if none accepted. Gateway status records are neither accepted nor rejected.
+// The response body summarizes the batch:
+//
+//	{"accepted": 2, "rejected": 1, "statuses": 1, "errors": [{"index": 1, "error": "..."}]}
+type HTTPHandler struct {
+	// Called for each message, if not nil
+	Handler Handler
+	// Called for each request with all accepted messages, if not nil
+	BatchHandler BatchHandler
+	// Called for each gateway status record, if not nil
+	StatusHandler StatusHandler
+	// Required basic auth credential, if Username is not empty
+	Username string
+	Password string
+	// Required bearer token, if not empty.
+	// Either one is accepted if both basic auth and bearer token are set
+	Token string
+	// Max size of request body after decompressed, 10MB if zero
+	MaxBodySize int64
+
+	stats statsTable
+}
+
+type batchError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type batchResult struct {
+	Accepted int          `json:"accepted"`
+	Rejected int          `json:"rejected"`
+	Statuses int          `json:"statuses,omitempty"`
+	Errors   []batchError `json:"errors,omitempty"`
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorized(r) {
+		if h.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="igs"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="igs"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	src := SourceAddr(r.RemoteAddr)
+	h.stats.update(src, func(st *Stats) { st.Packets++ })
+
+	body, status, err := h.readBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	msgs, sts, err := igs.ParseBatchStatus(body, "")
+	result := batchResult{Accepted: len(msgs), Statuses: len(sts)}
+	if errs, ok := err.(igs.BatchError); ok {
+		for _, e := range errs {
+			result.Errors = append(result.Errors, batchError{e.Index, e.Err.Error()})
+		}
+		result.Rejected = len(errs)
+	} else if err != nil {
+		// malformed JSON document
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.stats.update(src, func(st *Stats) {
+		st.Lines += uint64(result.Accepted + result.Rejected + result.Statuses)
+		st.Messages += uint64(result.Accepted)
+		st.Statuses += uint64(result.Statuses)
+		st.Failures += uint64(result.Rejected)
+	})
+
+	if h.StatusHandler != nil {
+		for _, st := range sts {
+			h.StatusHandler(r.Context(), st, src)
+		}
+	}
+	if h.Handler != nil {
+		for _, m := range msgs {
+			h.Handler.HandleMessage(r.Context(), m, src)
+		}
+	}
+	if h.BatchHandler != nil && len(msgs) > 0 {
+		if err := h.BatchHandler(r.Context(), msgs, src); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	status = http.StatusOK
+	if result.Rejected > 0 && result.Accepted > 0 {
+		status = http.StatusMultiStatus
+	} else if result.Rejected > 0 {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *HTTPHandler) authorized(r *http.Request) bool {
+	if h.Username == "" && h.Token == "" {
+		return true
+	}
+	if h.Username != "" {
+		if user, pass, ok := r.BasicAuth(); ok && equal(user, h.Username) && equal(pass, h.Password) {
+			return true
+		}
+	}
+	if h.Token != "" {
+		auth := r.Header.Get("Authorization")
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") && equal(auth[7:], h.Token) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Read the (decompressed) request body, returns error and status code if failed
+func (h *HTTPHandler) readBody(r *http.Request) ([]byte, int, error) {
+	max := h.MaxBodySize
+	if max <= 0 {
+		max = defaultMaxBodySize
+	}
+	var body io.Reader = &limitReader{r.Body, max}
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			if errors.Is(err, errBodyTooLarge) {
+				return nil, http.StatusRequestEntityTooLarge, err
+			}
+			return nil, http.StatusBadRequest, err
+		}
+		defer zr.Close()
+		// limit the decompressed size as well
+		body = &limitReader{zr, max}
+	default:
+		return nil, http.StatusUnsupportedMediaType, errors.New("unsupported content encoding")
+	}
+	data, err := ioutil.ReadAll(body)
+	if errors.Is(err, errBodyTooLarge) {
+		return nil, http.StatusRequestEntityTooLarge, err
+	} else if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return data, 0, nil
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// Reader fails with errBodyTooLarge once more than n bytes are read
+type limitReader struct {
+	r io.Reader
+	n int64 // bytes remaining
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	// read one more byte to tell if the limit is exceeded
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// Statistics of each source address, requests are counted as packets
+func (h *HTTPHandler) Stats() map[SourceAddr]Stats {
+	return h.stats.snapshot()
+}
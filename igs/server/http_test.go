@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	var got []*igs.Message
+	var batches int
+	h := &HTTPHandler{
+		Handler: HandlerFunc(func(ctx context.Context, m *igs.Message, src SourceAddr) {
+			got = append(got, m)
+		}),
+		BatchHandler: func(ctx context.Context, msgs []*igs.Message, src SourceAddr) error {
+			batches++
+			return nil
+		},
+	}
+	jsonBody := `[{"type":"GPRP","beacon":"D8714D784B4F","gateway":"F008D1789200","rssi":-45,"payload":"0201"}]`
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(testMessage))
+	zw.Close()
+
+	cases := []struct {
+		body     []byte
+		encoding string
+		status   int
+		accepted int
+		rejected int
+	}{
+		{[]byte(testMessage + "\n" + testMessage), "", http.StatusOK, 2, 0},
+		{[]byte(jsonBody), "", http.StatusOK, 1, 0},
+		{gz.Bytes(), "gzip", http.StatusOK, 1, 0},
+		{[]byte(testMessage + "\n$GPRP,invalid"), "", http.StatusMultiStatus, 1, 1},
+		{[]byte("$GPRP,invalid"), "", http.StatusBadRequest, 0, 1},
+		{[]byte(`[{"type":`), "", http.StatusBadRequest, 0, 1},
+		{[]byte(testMessage), "gzip", http.StatusBadRequest, 0, 0},
+		{[]byte(testMessage), "br", http.StatusUnsupportedMediaType, 0, 0},
+	}
+	for i, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(c.body))
+		if c.encoding != "" {
+			req.Header.Set("Content-Encoding", c.encoding)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Errorf("case %v: status = %v, want %v", i, rec.Code, c.status)
+		}
+		if rec.Header().Get("Content-Type") != "application/json" {
+			continue
+		}
+		var result batchResult
+		json.NewDecoder(rec.Body).Decode(&result)
+		if result.Accepted != c.accepted || result.Rejected != c.rejected || len(result.Errors) != c.rejected {
+			t.Errorf("case %v: result = %+v, want %v accepted, %v rejected", i, result, c.accepted, c.rejected)
+		}
+	}
+	if len(got) != 5 || batches != 4 {
+		t.Errorf("handled %v messages in %v batches, want 5 in 4", len(got), batches)
+	}
+	st := h.Stats()["192.0.2.1:1234"]
+	if st.Packets != 8 || st.Messages != 5 || st.Failures != 3 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestHTTPHandler_Status(t *testing.T) {
+	var sts []*igs.Status
+	h := &HTTPHandler{
+		StatusHandler: func(ctx context.Context, st *igs.Status, src SourceAddr) {
+			sts = append(sts, st)
+		},
+	}
+	body := "$HBRP,F008D1789200,1630382368\n$HBRP,F008D1789201,1630382368\n"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var result batchResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result.Accepted != 0 || result.Rejected != 0 || result.Statuses != 2 || len(sts) != 2 {
+		t.Errorf("result = %+v, %v status handled", result, len(sts))
+	}
+	st := h.Stats()["192.0.2.1:1234"]
+	if st.Lines != 2 || st.Statuses != 2 || st.Failures != 0 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestHTTPHandler_Auth(t *testing.T) {
+	h := &HTTPHandler{Username: "user", Password: "pass", Token: "secret"}
+	cases := []struct {
+		setup  func(r *http.Request)
+		status int
+	}{
+		{func(r *http.Request) {}, http.StatusUnauthorized},
+		{func(r *http.Request) { r.SetBasicAuth("user", "pass") }, http.StatusOK},
+		{func(r *http.Request) { r.SetBasicAuth("user", "wrong") }, http.StatusUnauthorized},
+		{func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }, http.StatusOK},
+		{func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+	}
+	for i, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testMessage))
+		c.setup(req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Errorf("case %v: status = %v, want %v", i, rec.Code, c.status)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %v, want %v", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHTTPHandler_Limits(t *testing.T) {
+	h := &HTTPHandler{
+		MaxBodySize: 16,
+		BatchHandler: func(ctx context.Context, msgs []*igs.Message, src SourceAddr) error {
+			return errors.New("storage unavailable")
+		},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testMessage)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	// decompressed body exceeds the limit
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(bytes.Repeat([]byte("\n"), 1024))
+	zw.Close()
+	h.MaxBodySize = int64(gz.Len())
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gz.Bytes()))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	h.MaxBodySize = 0
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testMessage)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,99 @@
+// Package server provides receivers for messages pushed by iGS gateways
+package server
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Address of the gateway the message comes from
+type SourceAddr string
+
+// Handler of received messages
+type Handler interface {
+	HandleMessage(ctx context.Context, m *igs.Message, src SourceAddr)
+}
+
+// Adapter to use ordinary function as Handler
+type HandlerFunc func(ctx context.Context, m *igs.Message, src SourceAddr)
+
+// HandleMessage calls f(ctx, m, src)
+func (f HandlerFunc) HandleMessage(ctx context.Context, m *igs.Message, src SourceAddr) {
+	f(ctx, m, src)
+}
+
+// Handler of gateway status records, e.g. heartbeats
+type StatusHandler func(ctx context.Context, st *igs.Status, src SourceAddr)
+
+// Statistics of a source address
+type Stats struct {
+	// Packets (or requests) received
+	Packets uint64
+	// Non-blank lines received
+	Lines uint64
+	// Messages dispatched to handler
+	Messages uint64
+	// Gateway status records received, which are not failures
+	Statuses uint64
+	// Messages failed to be parsed
+	Failures uint64
+	// Packets dropped due to full queue
+	Dropped uint64
+}
+
+// Statistics of all source addresses, safe for concurrent use
+type statsTable struct {
+	mu    sync.Mutex
+	table map[SourceAddr]*Stats
+}
+
+func (t *statsTable) update(src SourceAddr, f func(*Stats)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.table == nil {
+		t.table = map[SourceAddr]*Stats{}
+	}
+	st, ok := t.table[src]
+	if !ok {
+		st = &Stats{}
+		t.table[src] = st
+	}
+	f(st)
+}
+
+func (t *statsTable) snapshot() map[SourceAddr]Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	x := make(map[SourceAddr]Stats, len(t.table))
+	for src, st := range t.table {
+		x[src] = *st
+	}
+	return x
+}
+
+// Parse all messages in data and dispatch them to handler,
+// status records are dispatched to status handler if not nil
+func dispatch(ctx context.Context, h Handler, status StatusHandler, data []byte, src SourceAddr, stats *statsTable) {
+	sc := igs.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	n, statuses := uint64(0), uint64(0)
+	sc.Status = func(line int, st *igs.Status) {
+		statuses++
+		if status != nil {
+			status(ctx, st, src)
+		}
+	}
+	for sc.Scan() {
+		h.HandleMessage(ctx, sc.Message(), src)
+		n++
+	}
+	stats.update(src, func(st *Stats) {
+		st.Lines += uint64(sc.Lines())
+		st.Messages += n
+		st.Statuses += statuses
+		st.Failures += uint64(sc.Skipped())
+	})
+}
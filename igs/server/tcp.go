@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+const defaultIdleTimeout = 5 * time.Minute
+
+// Session of a gateway connection
+type Session struct {
+	// Remote address of the connection
+	RemoteAddr SourceAddr
+	// Time connected
+	FirstSeen time.Time
+	// Time of the last received line
+	LastSeen time.Time
+	// Gateway mac address learned from the stream, empty before any message received
+	Gateway string
+	// Non-blank lines received
+	Lines uint64
+	// Messages dispatched to handler
+	Messages uint64
+	// Messages failed to be parsed
+	Failures uint64
+}
+
+// TCP server receives messages from iGS gateways in TCP client mode,
+// gateways keep long-lived connections and stream messages line by line
+type TCPServer struct {
+	// Address to listen on, e.g. ":8080"
+	Addr string
+	// Handler of received messages, called sequentially for each connection
+	Handler Handler
+	// Handler of gateway status records, e.g. heartbeats, if not nil
+	StatusHandler StatusHandler
+	// Called when a gateway connected, if not nil
+	OnConnect func(s Session)
+	// Called when a gateway disconnected, with the reason (nil for EOF), if not nil
+	OnDisconnect func(s Session, err error)
+	// Close the connection if nothing received within the duration,
+	// for detecting half-open connections. 5 minutes if zero, disabled if negative
+	IdleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[net.Conn]*Session
+}
+
+// Listen on s.Addr and serve until ctx is done
+func (s *TCPServer) ListenAndServe(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Accept connections from l until ctx is done, l and all connections are closed when returns.
+// Temporary accept errors are retried with backoff. Returns nil when ctx is done,
+// or the accept error if l is closed or fails permanently.
+func (s *TCPServer) Serve(ctx context.Context, l net.Listener) error {
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		l.Close()
+		s.mu.Lock()
+		for conn := range s.sessions {
+			conn.Close()
+		}
+		s.mu.Unlock()
+	}()
+
+	var err error
+	var delay time.Duration // backoff of temporary accept errors
+	for {
+		var conn net.Conn
+		if conn, err = l.Accept(); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() && ctx.Err() == nil {
+				// e.g. too many open files, retry as net/http does
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else {
+					delay *= 2
+				}
+				if delay > time.Second {
+					delay = time.Second
+				}
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+				}
+				continue
+			}
+			break
+		}
+		delay = 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+	close(done)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (s *TCPServer) serveConn(ctx context.Context, conn net.Conn) {
+	now := time.Now()
+	sess := &Session{
+		RemoteAddr: SourceAddr(conn.RemoteAddr().String()),
+		FirstSeen:  now,
+		LastSeen:   now,
+	}
+	s.mu.Lock()
+	if s.sessions == nil {
+		s.sessions = map[net.Conn]*Session{}
+	}
+	s.sessions[conn] = sess
+	s.mu.Unlock()
+	if ctx.Err() != nil {
+		// closing, the connection may be missed
+		conn.Close()
+	}
+	if s.OnConnect != nil {
+		s.OnConnect(*sess)
+	}
+
+	timeout := s.IdleTimeout
+	if timeout == 0 {
+		timeout = defaultIdleTimeout
+	}
+	sc := igs.NewScanner(&idleReader{conn, timeout})
+	sc.Error = func(line int, text string, err error) {
+		s.update(sess, sc, "", 0)
+	}
+	sc.Status = func(line int, st *igs.Status) {
+		s.update(sess, sc, st.Gateway(), 0)
+		if s.StatusHandler != nil {
+			s.StatusHandler(ctx, st, sess.RemoteAddr)
+		}
+	}
+	for sc.Scan() {
+		m := sc.Message()
+		s.update(sess, sc, m.Gateway(), 1)
+		if s.Handler != nil {
+			s.Handler.HandleMessage(ctx, m, sess.RemoteAddr)
+		}
+	}
+	conn.Close()
+
+	s.mu.Lock()
+	delete(s.sessions, conn)
+	final := *sess
+	s.mu.Unlock()
+	if s.OnDisconnect != nil {
+		s.OnDisconnect(final, sc.Err())
+	}
+}
+
+func (s *TCPServer) update(sess *Session, sc *igs.Scanner, gateway string, messages uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess.LastSeen = time.Now()
+	if gateway != "" {
+		sess.Gateway = gateway
+	}
+	sess.Lines = uint64(sc.Lines())
+	sess.Messages += messages
+	sess.Failures = uint64(sc.Skipped())
+}
+
+// Snapshot of sessions of current connections
+func (s *TCPServer) Sessions() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x := make([]Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		x = append(x, *sess)
+	}
+	return x
+}
+
+// Reader refreshes the read deadline before each read
+type idleReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	if r.timeout > 0 {
+		r.conn.SetReadDeadline(time.Now().Add(r.timeout))
+	}
+	return r.conn.Read(p)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+func TestTCPServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make(chan *igs.Message, 10)
+	sts := make(chan *igs.Status, 10)
+	connected := make(chan Session, 2)
+	disconnected := make(chan Session, 2)
+	s := &TCPServer{
+		Handler: HandlerFunc(func(ctx context.Context, m *igs.Message, src SourceAddr) {
+			msgs <- m
+		}),
+		StatusHandler: func(ctx context.Context, st *igs.Status, src SourceAddr) {
+			sts <- st
+		},
+		OnConnect:    func(sess Session) { connected <- sess },
+		OnDisconnect: func(sess Session, err error) { disconnected <- sess },
+		IdleTimeout:  500 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Serve(ctx, l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sess := <-connected
+	if sess.RemoteAddr != SourceAddr(conn.LocalAddr().String()) || sess.Gateway != "" {
+		t.Errorf("connected session = %+v", sess)
+	}
+	// line split across writes, the pause only makes separate reads likely
+	conn.Write([]byte(testMessage[:20]))
+	time.Sleep(time.Millisecond)
+	conn.Write([]byte(testMessage[20:] + "\r\n$GPRP,invalid\r\n$HBRP,F008D1789201\r\n"))
+	select {
+	case <-msgs:
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+	// session is updated before the status record is handled, which is the last line
+	select {
+	case <-sts:
+	case <-time.After(time.Second):
+		t.Fatal("status not received")
+	}
+	if x := s.Sessions(); len(x) != 1 || x[0].Messages != 1 || x[0].Gateway != "F008D1789201" {
+		t.Errorf("Sessions() = %+v", x)
+	}
+
+	// idle connection is closed
+	select {
+	case sess = <-disconnected:
+		if sess.Lines != 3 || sess.Messages != 1 || sess.Failures != 1 || !sess.LastSeen.After(sess.FirstSeen) {
+			t.Errorf("disconnected session = %+v", sess)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection not closed")
+	}
+	if len(s.Sessions()) != 0 {
+		t.Errorf("Sessions() = %+v", s.Sessions())
+	}
+
+	// connections are closed when shutting down
+	conn2, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	<-connected
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+	select {
+	case <-disconnected:
+	default:
+		t.Errorf("connection not closed when shutting down")
+	}
+}
+
+type tempError struct{}
+
+func (tempError) Error() string   { return "too many open files" }
+func (tempError) Timeout() bool   { return false }
+func (tempError) Temporary() bool { return true }
+
+// Listener fails with temporary errors before accepting
+type flakyListener struct {
+	net.Listener
+	failures int
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if l.failures > 0 {
+		l.failures--
+		return nil, tempError{}
+	}
+	return l.Listener.Accept()
+}
+
+func TestTCPServer_TemporaryError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connected := make(chan Session, 1)
+	s := &TCPServer{OnConnect: func(sess Session) { connected <- sess }}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Serve(ctx, &flakyListener{l, 3}) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("not accepted after temporary errors")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
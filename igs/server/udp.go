@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"sync"
+)
+
+// Policy when the queue of received packets is full
+type DropPolicy int
+
+const (
+	// Stop reading from socket until the queue has room (back-pressure),
+	// packets may be dropped by the kernel instead
+	Block DropPolicy = iota
+	// Discard the incoming packet
+	DropNewest
+	// Discard the oldest packet in queue
+	DropOldest
+)
+
+const (
+	defaultQueueSize     = 1024
+	defaultMaxPacketSize = 65535
+)
+
+type packet struct {
+	data []byte
+	src  SourceAddr
+}
+
+// UDP server receives messages from iGS gateways in UDP client mode
+type UDPServer struct {
+	// Address to listen on, e.g. ":8080"
+	Addr string
+	// Handler of received messages
+	Handler Handler
+	// Handler of gateway status records, e.g. heartbeats, if not nil
+	StatusHandler StatusHandler
+	// Number of workers parsing packets and calling handler,
+	// runtime.NumCPU() if zero
+	Workers int
+	// Number of packets could be queued for workers, 1024 if zero
+	QueueSize int
+	// Policy when the queue is full
+	DropPolicy DropPolicy
+	// Max size of a packet, 65535 if zero
+	MaxPacketSize int
+
+	stats statsTable
+}
+
+// Listen on s.Addr and serve until ctx is done
+func (s *UDPServer) ListenAndServe(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, conn)
+}
+
+// Serve packets from conn until ctx is done, conn is closed when returns.
+// Returns nil when ctx is done, queued packets are still dispatched before returns.
+func (s *UDPServer) Serve(ctx context.Context, conn net.PacketConn) error {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	size := s.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	queue := make(chan packet, size)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pkt := range queue {
+				dispatch(ctx, s.Handler, s.StatusHandler, pkt.data, pkt.src, &s.stats)
+			}
+		}()
+	}
+	// close conn to stop reading when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	err := s.read(ctx, conn, queue)
+	close(queue)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (s *UDPServer) read(ctx context.Context, conn net.PacketConn, queue chan packet) error {
+	max := s.MaxPacketSize
+	if max <= 0 {
+		max = defaultMaxPacketSize
+	}
+	buf := make([]byte, max)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		pkt := packet{make([]byte, n), SourceAddr(addr.String())}
+		copy(pkt.data, buf[:n])
+		s.stats.update(pkt.src, func(st *Stats) { st.Packets++ })
+		s.enqueue(ctx, queue, pkt)
+	}
+}
+
+func (s *UDPServer) enqueue(ctx context.Context, queue chan packet, pkt packet) {
+	for {
+		select {
+		case queue <- pkt:
+			return
+		default:
+		}
+		switch s.DropPolicy {
+		case DropNewest:
+			s.stats.update(pkt.src, func(st *Stats) { st.Dropped++ })
+			return
+		case DropOldest:
+			select {
+			case old := <-queue:
+				s.stats.update(old.src, func(st *Stats) { st.Dropped++ })
+			default:
+			}
+		default:
+			select {
+			case queue <- pkt:
+			case <-ctx.Done():
+				s.stats.update(pkt.src, func(st *Stats) { st.Dropped++ })
+			}
+			return
+		}
+	}
+}
+
+// Statistics of each source address
+func (s *UDPServer) Stats() map[SourceAddr]Stats {
+	return s.stats.snapshot()
+}
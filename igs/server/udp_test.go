@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+const testMessage = "$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.698"
+
+func startUDPServer(t *testing.T, s *UDPServer) (addr net.Addr, stop func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Serve(ctx, conn) }()
+	return conn.LocalAddr(), func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	}
+}
+
+func TestUDPServer(t *testing.T) {
+	msgs := make(chan *igs.Message, 10)
+	sts := make(chan *igs.Status, 10)
+	s := &UDPServer{
+		Handler: HandlerFunc(func(ctx context.Context, m *igs.Message, src SourceAddr) {
+			msgs <- m
+		}),
+		StatusHandler: func(ctx context.Context, st *igs.Status, src SourceAddr) {
+			sts <- st
+		},
+		Workers: 2,
+	}
+	addr, stop := startUDPServer(t, s)
+
+	client, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte(testMessage + "\r\n" + testMessage + "\r\n"))
+	client.Write([]byte("$GPRP,invalid\n\n" + testMessage + "\n$HBRP,F008D1789200,1630382368"))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case m := <-msgs:
+			if m.Beacon() != "D8714D784B4F" {
+				t.Errorf("received %v", m)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("received %v messages, want 3", i)
+		}
+	}
+	select {
+	case st := <-sts:
+		if st.Gateway() != "F008D1789200" {
+			t.Errorf("received %v", st)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("status not received")
+	}
+	stop()
+
+	src := SourceAddr(client.LocalAddr().String())
+	want := Stats{Packets: 2, Lines: 5, Messages: 3, Statuses: 1, Failures: 1}
+	if got := s.Stats()[src]; got != want {
+		t.Errorf("Stats()[%v] = %+v, want %+v", src, got, want)
+	}
+}
+
+func TestUDPServer_DropNewest(t *testing.T) {
+	block := make(chan struct{})
+	received := make(chan struct{}, 10)
+	s := &UDPServer{
+		Handler: HandlerFunc(func(ctx context.Context, m *igs.Message, src SourceAddr) {
+			received <- struct{}{}
+			<-block
+		}),
+		Workers:    1,
+		QueueSize:  1,
+		DropPolicy: DropNewest,
+	}
+	addr, stop := startUDPServer(t, s)
+	client, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	src := SourceAddr(client.LocalAddr().String())
+
+	// first one is handling by the worker
+	client.Write([]byte(testMessage))
+	<-received
+	// second one is queued, the rest are dropped
+	for i := 0; i < 3; i++ {
+		client.Write([]byte(testMessage))
+	}
+	deadline := time.Now().Add(time.Second)
+	for s.Stats()[src].Packets < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(block)
+	stop()
+
+	if got := s.Stats()[src]; got.Packets != 4 || got.Dropped != 2 || got.Messages != 2 {
+		t.Errorf("Stats()[%v] = %+v, want 4 packets, 2 dropped, 2 messages", src, got)
+	}
+}
@@ -0,0 +1,64 @@
+package igs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts of date time string timestamp, the ones without time zone are in UTC
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Parse timestamp string reported by iGS gateway, accepts
+//   - epoch time in seconds with arbitrary fractional precision, e.g. 1630382368.698
+//   - epoch time integer in milliseconds, microseconds or nanoseconds, e.g. 1630382368698
+//   - RFC3339 / ISO-8601 date time, e.g. 2021-08-31T03:59:28.698Z
+func ParseTimestamp(s string) (time.Time, error) {
+	if len(s) > 0 && s[0] >= '0' && s[0] <= '9' && !strings.ContainsAny(s, "-:") {
+		return parseEpoch(s)
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("igs: unknown timestamp format: " + strconv.Quote(s))
+}
+
+func parseEpoch(s string) (time.Time, error) {
+	frac := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, frac = s[:i], s[i+1:]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// unit is determined by magnitude, seconds before year 5138
+	unit := int64(time.Second)
+	switch {
+	case v >= 1e17:
+		unit = int64(time.Nanosecond)
+	case v >= 1e14:
+		unit = int64(time.Microsecond)
+	case v >= 1e11:
+		unit = int64(time.Millisecond)
+	}
+	// fractional part of unit in nanoseconds, digits beyond nanoseconds are truncated
+	var ns int64
+	scale := unit
+	for i := 0; i < len(frac); i++ {
+		if frac[i] < '0' || frac[i] > '9' {
+			return time.Time{}, errors.New("igs: invalid fraction: " + strconv.Quote(frac))
+		}
+		scale /= 10
+		ns += int64(frac[i]-'0') * scale
+	}
+	return time.Unix(v/(int64(time.Second)/unit), v%(int64(time.Second)/unit)*unit+ns), nil
+}
@@ -0,0 +1,70 @@
+package igs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	cases := []struct {
+		input string
+		want  time.Time
+	}{
+		{"1630382368", time.Unix(1630382368, 0)},
+		{"1630382368.6", time.Unix(1630382368, 600000000)},
+		{"1630382368.698", time.Unix(1630382368, 698000000)},
+		{"1630382368.000698", time.Unix(1630382368, 698000)},
+		{"1630382368.1234567891", time.Unix(1630382368, 123456789)},
+		{"1630382368698", time.Unix(1630382368, 698000000)},
+		{"1630382368698123", time.Unix(1630382368, 698123000)},
+		{"1630382368698123456", time.Unix(1630382368, 698123456)},
+		{"2021-08-31T03:59:28.698Z", time.Unix(1630382368, 698000000)},
+		{"2021-08-31T11:59:28+08:00", time.Unix(1630382368, 0)},
+		{"2021-08-31T03:59:28.698", time.Unix(1630382368, 698000000)},
+		{"2021-08-31 03:59:28", time.Unix(1630382368, 0)},
+	}
+	for _, c := range cases {
+		got, err := ParseTimestamp(c.input)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q) error = %v", c.input, err)
+		} else if !got.Equal(c.want) {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", c.input, got, c.want)
+		}
+	}
+	for _, input := range []string{"", "abc", "1630382368.6x", "16303823x8", "2021-08-31", "-1630382368"} {
+		if got, err := ParseTimestamp(input); err == nil {
+			t.Errorf("ParseTimestamp(%q) = %v, want error", input, got)
+		}
+	}
+}
+
+func TestMessage_Timestamp(t *testing.T) {
+	fallback := time.Unix(1, 0)
+
+	m := Parse("$GPRP,D8714D784B4F,F008D1789200,-45,0201")
+	if ts, err := m.TimestampE(); ts != nil || err != nil {
+		t.Errorf("TimestampE() = %v, %v, want nil, nil", ts, err)
+	}
+	if got := m.TimestampOr(fallback); !got.Equal(fallback) {
+		t.Errorf("TimestampOr() = %v, want %v", got, fallback)
+	}
+	if got := m.Time(); !got.Equal(m.Received()) || got.IsZero() {
+		t.Errorf("Time() = %v, want received time %v", got, m.Received())
+	}
+
+	m = Parse("$GPRP,D8714D784B4F,F008D1789200,-45,0201,163038x368")
+	ts, err := m.TimestampE()
+	var perr *ParseError
+	if ts != nil || !errors.As(err, &perr) || !errors.Is(err, ErrInvalidTimestamp) || perr.Offset != 41 {
+		t.Errorf("TimestampE() = %v, %v, want ErrInvalidTimestamp at offset 41", ts, err)
+	}
+	if m.Timestamp() != nil {
+		t.Errorf("Timestamp() = %v, want nil", m.Timestamp())
+	}
+
+	m = Parse("$GPRP,D8714D784B4F,F008D1789200,-45,0201,1630382368.6")
+	if got := m.TimestampOr(fallback); !got.Equal(time.Unix(1630382368, 600000000)) {
+		t.Errorf("TimestampOr() = %v", got)
+	}
+}
@@ -0,0 +1,260 @@
+// Package locate estimates indoor position of beacons from multi-gateway RSSI
+package locate
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/dedup"
+	"github.com/ingics/ingics-parser-go/ibs"
+	"github.com/ingics/ingics-parser-go/igs"
+	"github.com/ingics/ingics-parser-go/rssi"
+)
+
+// Position on the map, in meters
+type Point struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Floor int     `json:"floor"`
+}
+
+// Gateway installed on the map
+type Gateway struct {
+	// Gateway (IGSXX) mac address, separators ':' and '-' are allowed
+	MAC string `json:"mac"`
+	Point
+}
+
+// Map configuration of gateway positions, safe for concurrent use.
+// Gateways should not be modified after the first Lookup.
+type Map struct {
+	Gateways []Gateway `json:"gateways"`
+
+	once  sync.Once
+	index map[string]Point
+}
+
+// Map constructor
+func NewMap(gateways ...Gateway) *Map {
+	m := &Map{Gateways: gateways}
+	m.once.Do(m.build)
+	return m
+}
+
+// Map constructor, input is the JSON configuration, for example
+//
+//	{"gateways": [{"mac": "F008D1789200", "x": 0, "y": 0, "floor": 1}]}
+func ParseMap(data []byte) (*Map, error) {
+	m := &Map{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	m.once.Do(m.build)
+	return m, nil
+}
+
+func (m *Map) build() {
+	m.index = make(map[string]Point, len(m.Gateways))
+	for _, gw := range m.Gateways {
+		mac, _ := igs.NormalizeMAC(gw.MAC)
+		m.index[mac] = gw.Point
+	}
+}
+
+// Position of the gateway
+func (m *Map) Lookup(gateway string) (Point, bool) {
+	m.once.Do(m.build)
+	mac, _ := igs.NormalizeMAC(gateway)
+	p, ok := m.index[mac]
+	return p, ok
+}
+
+// Method of position estimation
+type Method int
+
+const (
+	// Position of the gateway with the strongest RSSI
+	Nearest Method = iota
+	// Centroid of gateways weighted by inverse square of estimated distance
+	Centroid
+	// Least-squares trilateration of estimated distances
+	Trilateration
+)
+
+// Stringer of Method
+func (m Method) String() string {
+	switch m {
+	case Centroid:
+		return "Centroid"
+	case Trilateration:
+		return "Trilateration"
+	}
+	return "Nearest"
+}
+
+// Estimated position of a beacon
+type Position struct {
+	Point
+	// Beacon (Tag) BLE mac address
+	Beacon string
+	// Confidence radius in meters
+	Radius float64
+	// Method used for the estimation
+	Method Method
+	// Number of gateways used
+	Gateways int
+	// Time of the advertisement
+	Time time.Time
+}
+
+// Locator estimates beacon positions from deduplicated observations.
+// Observations of gateways not on the map are ignored, and only the gateways on the floor
+// of the strongest one are used. The method falls back to Centroid if trilateration is
+// not solvable (less than 3 gateways or all in a line), and to Nearest with 1 gateway.
+type Locator struct {
+	// Map of gateway positions
+	Map *Map
+	// Distance estimator of RSSI
+	Estimator rssi.Estimator
+	// Preferred method
+	Method Method
+	// Observations with RSSI lower than this are ignored, not applied if zero
+	MinRSSI int
+}
+
+type anchor struct {
+	p Point
+	d float64
+}
+
+// Estimate the position of a merged advertisement, false if no gateway on the map
+func (l *Locator) Locate(m *dedup.Merged) (Position, bool) {
+	var payload *ibs.Payload
+	if b, err := hex.DecodeString(m.Payload); err == nil {
+		payload = ibs.Parse(b)
+	}
+	type observation struct {
+		dedup.Observation
+		Point
+		rssi.Estimate
+	}
+	obs := make([]observation, 0, len(m.Observations))
+	for _, o := range m.Observations {
+		p, ok := l.Map.Lookup(o.Gateway)
+		if !ok || (l.MinRSSI != 0 && o.RSSI < l.MinRSSI) {
+			continue
+		}
+		// missing (-127) or invalid RSSI has no distance
+		if e := l.Estimator.Estimate(float64(o.RSSI), payload); e.Proximity != rssi.Unknown {
+			obs = append(obs, observation{o, p, e})
+		}
+	}
+	if len(obs) == 0 {
+		return Position{}, false
+	}
+	sort.SliceStable(obs, func(i, j int) bool { return obs[i].RSSI > obs[j].RSSI })
+	floor := obs[0].Floor
+	var anchors []anchor
+	for _, o := range obs {
+		if o.Floor == floor {
+			anchors = append(anchors, anchor{o.Point, o.Distance})
+		}
+	}
+
+	pos := Position{Beacon: m.Beacon, Gateways: len(anchors), Time: m.First()}
+	method := l.Method
+	if method == Trilateration {
+		if p, r, ok := trilaterate(anchors); ok {
+			pos.Point, pos.Radius, pos.Method = p, r, Trilateration
+			return pos, true
+		}
+		method = Centroid
+	}
+	if method == Centroid && len(anchors) > 1 {
+		pos.Point, pos.Radius = centroid(anchors)
+		pos.Method = Centroid
+		return pos, true
+	}
+	pos.Point, pos.Radius, pos.Method = anchors[0].p, math.Max(anchors[0].d, 0), Nearest
+	pos.Gateways = 1
+	return pos, true
+}
+
+// Weighted centroid, radius is the weighted mean distance to the gateways
+func centroid(anchors []anchor) (Point, float64) {
+	var x, y, sum float64
+	for _, a := range anchors {
+		w := 1 / math.Max(a.d*a.d, 0.01)
+		x += w * a.p.X
+		y += w * a.p.Y
+		sum += w
+	}
+	p := Point{x / sum, y / sum, anchors[0].p.Floor}
+	var r float64
+	for _, a := range anchors {
+		w := 1 / math.Max(a.d*a.d, 0.01)
+		r += w * math.Hypot(a.p.X-p.X, a.p.Y-p.Y)
+	}
+	return p, r / sum
+}
+
+// Least-squares trilateration, the circle equations are linearized by subtracting the
+// last one, radius is the RMS error between estimated and solved distances
+func trilaterate(anchors []anchor) (Point, float64, bool) {
+	n := len(anchors)
+	if n < 3 {
+		return Point{}, 0, false
+	}
+	last := anchors[n-1]
+	// normal equations (A^T A) x = A^T b
+	var a11, a12, a22, b1, b2 float64
+	for _, a := range anchors[:n-1] {
+		ax := 2 * (last.p.X - a.p.X)
+		ay := 2 * (last.p.Y - a.p.Y)
+		b := a.d*a.d - last.d*last.d -
+			a.p.X*a.p.X + last.p.X*last.p.X -
+			a.p.Y*a.p.Y + last.p.Y*last.p.Y
+		a11 += ax * ax
+		a12 += ax * ay
+		a22 += ay * ay
+		b1 += ax * b
+		b2 += ay * b
+	}
+	det := a11*a22 - a12*a12
+	if math.Abs(det) < 1e-9*(a11*a22+1) {
+		// gateways in a line
+		return Point{}, 0, false
+	}
+	p := Point{(a22*b1 - a12*b2) / det, (a11*b2 - a12*b1) / det, last.p.Floor}
+	var sq float64
+	for _, a := range anchors {
+		e := math.Hypot(a.p.X-p.X, a.p.Y-p.Y) - a.d
+		sq += e * e
+	}
+	return p, math.Sqrt(sq / float64(n)), true
+}
+
+// Replay a recorded igs stream, messages are deduplicated within the window
+// by their time, f is called for each located advertisement in order of time.
+// Returns the error of reading, malformed lines are skipped.
+func (l *Locator) Replay(r io.Reader, window time.Duration, f func(Position)) error {
+	d := dedup.New(window, func(m *dedup.Merged) {
+		if pos, ok := l.Locate(m); ok {
+			f(pos)
+		}
+	})
+	var now time.Time
+	d.Clock = func() time.Time { return now }
+	s := igs.NewScanner(r)
+	for s.Scan() {
+		now = s.Message().Time()
+		d.Add(s.Message())
+	}
+	d.Flush()
+	return s.Err()
+}
@@ -0,0 +1,145 @@
+package locate
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testMap = `{"gateways": [
+	{"mac": "F0:08:D1:78:92:00", "x": 0, "y": 0, "floor": 1},
+	{"mac": "F0:08:D1:78:92:01", "x": 10, "y": 0, "floor": 1},
+	{"mac": "F0:08:D1:78:92:02", "x": 0, "y": 10, "floor": 1},
+	{"mac": "F0:08:D1:78:92:03", "x": 3, "y": 4, "floor": 2}
+]}`
+
+// beacon iBS03T (reference TX power -59) at (3, 4) of floor 1
+const testRecord = `$GPRP,D8714D784B4F,F008D1789200,-73,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.698
+$GPRP,D8714D784B4F,F008D1789201,-77,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.701
+$GPRP,D8714D784B4F,F008D1789202,-76,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.705
+$GPRP,D8714D784B4F,F008D1789203,-90,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.707
+$GPRP,D8714D784B4F,F008D17892FF,-40,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.710
+$GPRP,D8714D784B4F,F008D1789201,-60,02010612FF0D0083BC3101006D0B31000000140F0600,1630382369.698
+$GPRP,D8714D784B4F,F008D1789200,-70,02010612FF0D0083BC3101006D0B31000000140F0600,1630382369.702
+`
+
+func replay(t *testing.T, method Method) []Position {
+	m, err := ParseMap([]byte(testMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Locator{Map: m, Method: method}
+	var x []Position
+	if err := l.Replay(strings.NewReader(testRecord), 500*time.Millisecond, func(p Position) {
+		x = append(x, p)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(x) != 2 {
+		t.Fatalf("got %d positions, want 2", len(x))
+	}
+	return x
+}
+
+func TestTrilateration(t *testing.T) {
+	x := replay(t, Trilateration)
+	p := x[0]
+	if p.Method != Trilateration || p.Gateways != 3 || p.Floor != 1 || p.Beacon != "D8714D784B4F" {
+		t.Errorf("unexpected position %+v", p)
+	}
+	if d := math.Hypot(p.X-3, p.Y-4); d > 0.5 {
+		t.Errorf("position (%.2f, %.2f) is %.2f m away from (3, 4)", p.X, p.Y, d)
+	}
+	if p.Radius > 0.5 {
+		t.Errorf("Radius = %v", p.Radius)
+	}
+	if !p.Time.Equal(time.Unix(1630382368, 698000000)) {
+		t.Errorf("Time = %v", p.Time)
+	}
+	// falls back to centroid with 2 gateways
+	if p := x[1]; p.Method != Centroid || p.Gateways != 2 || p.X < 5 || p.Y != 0 {
+		t.Errorf("unexpected position %+v", p)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	p := replay(t, Centroid)[0]
+	if p.Method != Centroid || p.Gateways != 3 {
+		t.Errorf("unexpected position %+v", p)
+	}
+	// weighted to the nearest gateway (0, 0)
+	if p.X <= 0 || p.Y <= 0 || p.X >= 5 || p.Y >= 5 || p.Radius <= 0 {
+		t.Errorf("unexpected position %+v", p)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	p := replay(t, Nearest)[1]
+	if p.Method != Nearest || p.Gateways != 1 || p.X != 10 || p.Y != 0 || math.Abs(p.Radius-1.122) > 0.001 {
+		t.Errorf("unexpected position %+v", p)
+	}
+}
+
+func TestCollinear(t *testing.T) {
+	m := NewMap(
+		Gateway{"F008D1789200", Point{0, 0, 0}},
+		Gateway{"F008D1789201", Point{5, 0, 0}},
+		Gateway{"F008D1789202", Point{10, 0, 0}},
+	)
+	l := &Locator{Map: m, Method: Trilateration}
+	var x []Position
+	l.Replay(strings.NewReader(testRecord), time.Second, func(p Position) {
+		x = append(x, p)
+	})
+	if len(x) != 2 || x[0].Method != Centroid || x[0].Gateways != 3 || x[0].Y != 0 {
+		t.Errorf("unexpected positions %+v", x)
+	}
+
+	// weak observations ignored
+	l.MinRSSI = -75
+	x = nil
+	l.Replay(strings.NewReader(testRecord), time.Second, func(p Position) {
+		x = append(x, p)
+	})
+	if len(x) != 2 || x[0].Method != Nearest || x[0].Gateways != 1 || x[0].X != 0 {
+		t.Errorf("unexpected positions %+v", x)
+	}
+}
+
+func TestMissingRSSI(t *testing.T) {
+	m := NewMap(
+		Gateway{"F008D1789200", Point{0, 0, 0}},
+		Gateway{"F008D1789201", Point{10, 0, 0}},
+	)
+	l := &Locator{Map: m, Method: Centroid}
+	record := `$GPRP,D8714D784B4F,F008D1789200,-70,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.698
+$GPRP,D8714D784B4F,F008D1789201,-127,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368.701
+$GPRP,D8714D784B4F,F008D1789201,-127,02010612FF0D0083BC3101006D0B31000000140F0600,1630382369.701
+`
+	var x []Position
+	l.Replay(strings.NewReader(record), 500*time.Millisecond, func(p Position) {
+		x = append(x, p)
+	})
+	// the gateway not really hearing the beacon is not used
+	if len(x) != 1 || x[0].Method != Nearest || x[0].Gateways != 1 || x[0].X != 0 || x[0].Y != 0 {
+		t.Errorf("unexpected positions %+v", x)
+	}
+}
+
+func TestMap_ConcurrentLookup(t *testing.T) {
+	// index of a literal map is built on the first lookup
+	m := &Map{Gateways: []Gateway{{MAC: "f0-08-d1-78-92-00", Point: Point{X: 1, Y: 2, Floor: 1}}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p, ok := m.Lookup("F008D1789200"); !ok || p != (Point{1, 2, 1}) {
+				t.Errorf("Lookup() = %v, %v", p, ok)
+			}
+		}()
+	}
+	wg.Wait()
+}
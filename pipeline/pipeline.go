@@ -0,0 +1,175 @@
+// Package pipeline chains processing stages of decoded gateway reports
+package pipeline
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Stage processes decoded reports, returns false to drop the report.
+// Stages could modify (enrich) the report, and should be safe for concurrent use.
+type Stage interface {
+	Process(ctx context.Context, r *igs.Report) bool
+}
+
+// Adapter to use ordinary function as Stage
+type StageFunc func(ctx context.Context, r *igs.Report) bool
+
+// Process calls f(ctx, r)
+func (f StageFunc) Process(ctx context.Context, r *igs.Report) bool {
+	return f(ctx, r)
+}
+
+// Sink consumes reports passed all stages.
+// Reports are shared by all sinks and should not be modified.
+type Sink interface {
+	Consume(ctx context.Context, r *igs.Report) error
+}
+
+// Adapter to use ordinary function as Sink
+type SinkFunc func(ctx context.Context, r *igs.Report) error
+
+// Consume calls f(ctx, r)
+func (f SinkFunc) Consume(ctx context.Context, r *igs.Report) error {
+	return f(ctx, r)
+}
+
+// Metrics of a stage or sink
+type Metrics struct {
+	// Reports entered
+	In uint64
+	// Reports passed the stage, or consumed by the sink without error
+	Passed uint64
+	// Reports dropped by the stage, or failed to be consumed by the sink
+	Dropped uint64
+	// Name of the stage or sink
+	Name string
+}
+
+type stage struct {
+	Metrics
+	stage Stage
+}
+
+type sink struct {
+	Metrics
+	sink Sink
+}
+
+// Pipeline of stages and sinks, safe for concurrent use once built
+type Pipeline struct {
+	stages []*stage
+	sinks  []*sink
+	fanOut chan struct{} // slots of sink goroutines
+}
+
+// Pipeline constructor, fan-out is limited to GOMAXPROCS goroutines
+func New() *Pipeline {
+	return (&Pipeline{}).MaxFanOut(runtime.GOMAXPROCS(0))
+}
+
+// Limit the sink goroutines running at the same time across Process calls,
+// sinks are called on the goroutine of Process when the limit is reached.
+// All sinks are called on the goroutine of Process if n is not positive.
+func (p *Pipeline) MaxFanOut(n int) *Pipeline {
+	p.fanOut = nil
+	if n > 0 {
+		p.fanOut = make(chan struct{}, n)
+	}
+	return p
+}
+
+// Append a stage
+func (p *Pipeline) Stage(name string, s Stage) *Pipeline {
+	p.stages = append(p.stages, &stage{Metrics{Name: name}, s})
+	return p
+}
+
+// Append a sink
+func (p *Pipeline) Sink(name string, s Sink) *Pipeline {
+	p.sinks = append(p.sinks, &sink{Metrics{Name: name}, s})
+	return p
+}
+
+// Process the report through stages, and fan-out to sinks concurrently if passed.
+// A single sink is called on the goroutine of Process.
+// Returns false if dropped by a stage, and the first error of sinks.
+func (p *Pipeline) Process(ctx context.Context, r *igs.Report) (bool, error) {
+	for _, s := range p.stages {
+		atomic.AddUint64(&s.In, 1)
+		if !s.stage.Process(ctx, r) {
+			atomic.AddUint64(&s.Dropped, 1)
+			return false, nil
+		}
+		atomic.AddUint64(&s.Passed, 1)
+	}
+	if len(p.sinks) == 1 {
+		return true, p.sinks[0].consume(ctx, r)
+	}
+	errs := make([]error, len(p.sinks))
+	var wg sync.WaitGroup
+	for i, s := range p.sinks {
+		// the last one is called on this goroutine, which waits anyway
+		if i == len(p.sinks)-1 {
+			errs[i] = s.consume(ctx, r)
+			break
+		}
+		select {
+		case p.fanOut <- struct{}{}:
+		default:
+			// no free slot
+			errs[i] = s.consume(ctx, r)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s *sink) {
+			defer func() {
+				<-p.fanOut
+				wg.Done()
+			}()
+			errs[i] = s.consume(ctx, r)
+		}(i, s)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (s *sink) consume(ctx context.Context, r *igs.Report) error {
+	atomic.AddUint64(&s.In, 1)
+	err := s.sink.Consume(ctx, r)
+	if err != nil {
+		atomic.AddUint64(&s.Dropped, 1)
+	} else {
+		atomic.AddUint64(&s.Passed, 1)
+	}
+	return err
+}
+
+// Metrics of stages and sinks, in the order of appended
+func (p *Pipeline) Metrics() (stages []Metrics, sinks []Metrics) {
+	for _, s := range p.stages {
+		stages = append(stages, load(&s.Metrics))
+	}
+	for _, s := range p.sinks {
+		sinks = append(sinks, load(&s.Metrics))
+	}
+	return
+}
+
+func load(m *Metrics) Metrics {
+	return Metrics{
+		In:      atomic.LoadUint64(&m.In),
+		Passed:  atomic.LoadUint64(&m.Passed),
+		Dropped: atomic.LoadUint64(&m.Dropped),
+		Name:    m.Name,
+	}
+}
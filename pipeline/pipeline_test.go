@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+func decode(t *testing.T, line string) *igs.Report {
+	r, err := igs.Parse(line).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestPipeline(t *testing.T) {
+	var mu sync.Mutex
+	var consumed []string
+	p := New().
+		Stage("gateway", DenyGateways("F0:08:D1:78:92:FF")).
+		Stage("vendor", Vendors("INGICS TECHNOLOGY CO., LTD.")).
+		Stage("rssi", MinRSSI(-80)).
+		Stage("enrich", StageFunc(func(ctx context.Context, r *igs.Report) bool {
+			r.Gateway = "lobby"
+			return true
+		})).
+		Sink("store", SinkFunc(func(ctx context.Context, r *igs.Report) error {
+			mu.Lock()
+			defer mu.Unlock()
+			consumed = append(consumed, r.Beacon+"@"+r.Gateway)
+			return nil
+		})).
+		Sink("forward", SinkFunc(func(ctx context.Context, r *igs.Report) error {
+			return errors.New("unavailable")
+		}))
+
+	lines := []string{
+		"$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600",
+		"$GPRP,D8714D784B4F,F008D17892FF,-45,02010612FF0D0083BC3101006D0B31000000140F0600",
+		"$GPRP,D8714D784B4F,F008D1789200,-45,0201061AFF4C000215E2C56DB5DFFB48D2B060D0F5A71096E000000000C5",
+		"$GPRP,D8714D784B40,F008D1789200,-90,02010612FF0D0083BC3101006D0B31000000140F0600",
+	}
+	for i, line := range lines {
+		passed, err := p.Process(context.Background(), decode(t, line))
+		if passed != (i == 0) || (err != nil) != (i == 0) {
+			t.Errorf("Process(%v) = %v, %v", line, passed, err)
+		}
+	}
+	if !reflect.DeepEqual(consumed, []string{"D8714D784B4F@lobby"}) {
+		t.Errorf("consumed = %v", consumed)
+	}
+	stages, sinks := p.Metrics()
+	wantStages := []Metrics{{4, 3, 1, "gateway"}, {3, 2, 1, "vendor"}, {2, 1, 1, "rssi"}, {1, 1, 0, "enrich"}}
+	wantSinks := []Metrics{{1, 1, 0, "store"}, {1, 0, 1, "forward"}}
+	if !reflect.DeepEqual(stages, wantStages) || !reflect.DeepEqual(sinks, wantSinks) {
+		t.Errorf("Metrics() = %v, %v", stages, sinks)
+	}
+}
+
+func TestPipeline_FanOut(t *testing.T) {
+	r := decode(t, "$GPRP,D8714D784B4F,F008D1789200,-45,0201")
+	for _, n := range []int{0, 1, 8} {
+		var mu sync.Mutex
+		var order []int
+		p := New().MaxFanOut(n)
+		for i := 0; i < 4; i++ {
+			i := i
+			p.Sink("", SinkFunc(func(ctx context.Context, r *igs.Report) error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, i)
+				return nil
+			}))
+		}
+		for i := 0; i < 10; i++ {
+			if passed, err := p.Process(context.Background(), r); !passed || err != nil {
+				t.Fatalf("Process() = %v, %v", passed, err)
+			}
+		}
+		if len(order) != 40 {
+			t.Errorf("MaxFanOut(%v): %v sinks called, want 40", n, len(order))
+		}
+		// called in order without fan-out
+		if n == 0 && !reflect.DeepEqual(order[:4], []int{0, 1, 2, 3}) {
+			t.Errorf("MaxFanOut(0): order = %v", order[:4])
+		}
+	}
+}
+
+func TestStages(t *testing.T) {
+	r := decode(t, "$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600")
+	cases := []struct {
+		name  string
+		stage Stage
+		want  bool
+	}{
+		{"AllowGateways", AllowGateways("f0-08-d1-78-92-00"), true},
+		{"AllowGateways", AllowGateways("F008D1789201"), false},
+		{"DenyGateways", DenyGateways("F008D1789200"), false},
+		{"AllowBeacons", AllowBeacons("D8:71:4D:78:4B:4F"), true},
+		{"DenyBeacons", DenyBeacons("D8714D784B4F"), false},
+		{"Vendors", Vendors("Apple, Inc."), false},
+		{"Models", Models("iBS03T", "iBS05"), true},
+		{"Models", Models("iBS05"), false},
+		{"MinRSSI", MinRSSI(-45), true},
+		{"MinRSSI", MinRSSI(-44), false},
+	}
+	for _, c := range cases {
+		if got := c.stage.Process(context.Background(), r); got != c.want {
+			t.Errorf("%v.Process() = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	s := Sample(3)
+	var got []bool
+	for i := 0; i < 6; i++ {
+		got = append(got, s.Process(context.Background(), r))
+	}
+	if !reflect.DeepEqual(got, []bool{true, false, false, true, false, false}) {
+		t.Errorf("Sample(3) = %v", got)
+	}
+}
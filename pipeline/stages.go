@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Set of mac addresses, normalized to upper case without separators
+type macSet map[string]bool
+
+func newMACSet(macs []string) macSet {
+	set := macSet{}
+	for _, mac := range macs {
+		mac, _ = igs.NormalizeMAC(mac)
+		set[mac] = true
+	}
+	return set
+}
+
+func (set macSet) contains(mac string) bool {
+	mac, _ = igs.NormalizeMAC(mac)
+	return set[mac]
+}
+
+// Pass reports from the listed gateways only
+func AllowGateways(macs ...string) Stage {
+	set := newMACSet(macs)
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		return set.contains(r.Gateway)
+	})
+}
+
+// Drop reports from the listed gateways
+func DenyGateways(macs ...string) Stage {
+	set := newMACSet(macs)
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		return !set.contains(r.Gateway)
+	})
+}
+
+// Pass reports of the listed beacons only
+func AllowBeacons(macs ...string) Stage {
+	set := newMACSet(macs)
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		return set.contains(r.Beacon)
+	})
+}
+
+// Drop reports of the listed beacons
+func DenyBeacons(macs ...string) Stage {
+	set := newMACSet(macs)
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		return !set.contains(r.Beacon)
+	})
+}
+
+// Pass reports of the listed vendors only, see ibs.Payload.Vendor
+func Vendors(names ...string) Stage {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		if r.Payload == nil {
+			return false
+		}
+		name, ok := r.Payload.Vendor()
+		return ok && set[name]
+	})
+}
+
+// Pass reports of the listed product models only, see ibs.Payload.ProductModel
+func Models(names ...string) Stage {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		if r.Payload == nil {
+			return false
+		}
+		name, ok := r.Payload.ProductModel()
+		return ok && set[name]
+	})
+}
+
+// Pass reports with RSSI greater than or equal to the threshold
+func MinRSSI(rssi int) Stage {
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		return r.RSSI >= rssi
+	})
+}
+
+// Pass one of every n reports
+func Sample(n int) Stage {
+	var count uint64
+	return StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		return n <= 1 || (atomic.AddUint64(&count, 1)-1)%uint64(n) == 0
+	})
+}
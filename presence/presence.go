@@ -0,0 +1,213 @@
+// Package presence detects beacons entering, leaving and moving between zones,
+// with gateways as the zone identifiers
+package presence
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Type of presence event
+type EventType int
+
+const (
+	Enter EventType = iota
+	Exit
+	ZoneChange
+)
+
+// Stringer of EventType
+func (t EventType) String() string {
+	switch t {
+	case Enter:
+		return "Enter"
+	case Exit:
+		return "Exit"
+	case ZoneChange:
+		return "ZoneChange"
+	}
+	return "Unknown"
+}
+
+// Presence event of a beacon
+type Event struct {
+	Type EventType
+	// Beacon (Tag) BLE mac address
+	Beacon string
+	// Zone entered, or the zone left for Exit
+	Zone string
+	// Zone left, for ZoneChange only
+	From string
+	// Time of the event
+	Time time.Time
+}
+
+// Configuration of presence detection
+type Config struct {
+	// Min RSSI to be in range of a zone
+	EnterRSSI int
+	// Beacon is out of range of a zone if RSSI is lower than this (hysteresis),
+	// should not be greater than EnterRSSI
+	ExitRSSI int
+	// Min time a zone change (including enter and exit) should last before reported
+	MinDwell time.Duration
+	// Beacon is out of range of a gateway if not heard for the duration,
+	// 30 seconds if zero
+	AbsenceTimeout time.Duration
+	// Zone name of gateway mac address (separators ':' and '-' are allowed), the gateway
+	// mac address (normalized by igs.NormalizeMAC) is the zone name if not found.
+	// A zone of several gateways is in range if any of them is, with the strongest RSSI of them
+	Zones map[string]string
+}
+
+const defaultAbsenceTimeout = 30 * time.Second
+
+// Last in range reading of a gateway
+type gatewayReading struct {
+	zone     string
+	rssi     int
+	lastSeen time.Time
+}
+
+type beaconState struct {
+	zone     string
+	gateways map[string]gatewayReading
+	pending  bool // zone change pending for MinDwell
+	target   string
+	since    time.Time
+}
+
+// Presence engine, safe for concurrent use.
+// Messages are observed at the time of Engine clock rather than the timestamps in messages,
+// which are from the clocks of gateways and not in sync.
+type Engine struct {
+	// Clock of observations, time.Now if nil. Set it before observing any message,
+	// e.g. to the time of message for replaying a recorded stream.
+	// Tick should be called with the time of the same clock.
+	Clock func() time.Time
+
+	cfg     Config
+	zones   map[string]string // normalized gateway mac address to zone
+	mu      sync.Mutex
+	beacons map[string]*beaconState
+}
+
+// Engine constructor
+func New(cfg Config) *Engine {
+	if cfg.AbsenceTimeout <= 0 {
+		cfg.AbsenceTimeout = defaultAbsenceTimeout
+	}
+	zones := make(map[string]string, len(cfg.Zones))
+	for gateway, zone := range cfg.Zones {
+		gateway, _ = igs.NormalizeMAC(gateway)
+		zones[gateway] = zone
+	}
+	return &Engine{cfg: cfg, zones: zones, beacons: map[string]*beaconState{}}
+}
+
+func (e *Engine) now() time.Time {
+	if e.Clock != nil {
+		return e.Clock()
+	}
+	return time.Now()
+}
+
+func (e *Engine) zoneOf(gateway string) string {
+	if zone, ok := e.zones[gateway]; ok {
+		return zone
+	}
+	return gateway
+}
+
+// Observe a message at the time of Clock, returns the events triggered
+func (e *Engine) Observe(m *igs.Message) []Event {
+	now := e.now()
+	beacon, _ := igs.NormalizeMAC(m.Beacon())
+	gateway, _ := igs.NormalizeMAC(m.Gateway())
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st, ok := e.beacons[beacon]
+	if !ok {
+		st = &beaconState{gateways: map[string]gatewayReading{}}
+		e.beacons[beacon] = st
+	}
+	_, inRange := st.gateways[gateway]
+	if rssi := m.RSSI(); rssi >= e.cfg.EnterRSSI || (inRange && rssi >= e.cfg.ExitRSSI) {
+		st.gateways[gateway] = gatewayReading{e.zoneOf(gateway), rssi, now}
+	} else {
+		delete(st.gateways, gateway)
+	}
+	var events []Event
+	if evt, ok := e.evaluate(beacon, st, now, false); ok {
+		events = append(events, evt)
+	}
+	return events
+}
+
+// Check absence timeouts and pending zone changes at the time now, returns the events triggered
+func (e *Engine) Tick(now time.Time) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var events []Event
+	for beacon, st := range e.beacons {
+		timeout := false
+		for gateway, r := range st.gateways {
+			if now.Sub(r.lastSeen) >= e.cfg.AbsenceTimeout {
+				delete(st.gateways, gateway)
+				timeout = true
+			}
+		}
+		if evt, ok := e.evaluate(beacon, st, now, timeout); ok {
+			events = append(events, evt)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Beacon < events[j].Beacon })
+	return events
+}
+
+// Zone with the strongest RSSI, empty if not in range of any zone
+func (st *beaconState) best() string {
+	best, rssi := "", 0
+	for _, r := range st.gateways {
+		if best == "" || r.rssi > rssi || (r.rssi == rssi && r.zone < best) {
+			best, rssi = r.zone, r.rssi
+		}
+	}
+	return best
+}
+
+// Returns the event if zone changed for MinDwell, or exits due to absence timeout
+func (e *Engine) evaluate(beacon string, st *beaconState, now time.Time, timeout bool) (Event, bool) {
+	defer func() {
+		if st.zone == "" && len(st.gateways) == 0 && !st.pending {
+			delete(e.beacons, beacon)
+		}
+	}()
+	target := st.best()
+	if target == st.zone {
+		st.pending = false
+		return Event{}, false
+	}
+	if !st.pending || st.target != target {
+		st.pending, st.target, st.since = true, target, now
+	}
+	if now.Sub(st.since) < e.cfg.MinDwell && !(target == "" && timeout) {
+		return Event{}, false
+	}
+	evt := Event{Beacon: beacon, Zone: target, Time: now}
+	switch {
+	case st.zone == "":
+		evt.Type = Enter
+	case target == "":
+		evt.Type = Exit
+		evt.Zone = st.zone
+	default:
+		evt.Type = ZoneChange
+		evt.From = st.zone
+	}
+	st.zone, st.pending = target, false
+	return evt, true
+}
@@ -0,0 +1,127 @@
+package presence
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+const beacon = "D8714D784B4F"
+
+var t0 = time.Unix(1630382368, 0)
+
+// Observe at the time of sec, the timestamp in message is ignored
+func observe(e *Engine, gateway string, rssi int, sec float64) []Event {
+	e.Clock = func() time.Time { return at(sec) }
+	return e.Observe(igs.Parse(fmt.Sprintf("$GPRP,%v,%v,%v,0201,1000000000", beacon, gateway, rssi)))
+}
+
+func at(sec float64) time.Time {
+	return t0.Add(time.Duration(sec * float64(time.Second)))
+}
+
+func TestEngine(t *testing.T) {
+	e := New(Config{
+		EnterRSSI:      -70,
+		ExitRSSI:       -80,
+		MinDwell:       2 * time.Second,
+		AbsenceTimeout: 10 * time.Second,
+		Zones:          map[string]string{"F008D1789200": "lobby", "F008D1789201": "office"},
+	})
+	var got []Event
+	steps := []func() []Event{
+		// too weak to enter
+		func() []Event { return observe(e, "F008D1789200", -75, 0) },
+		// in range, enter after dwell
+		func() []Event { return observe(e, "F008D1789200", -65, 1) },
+		func() []Event { return observe(e, "F008D1789200", -75, 2) },
+		func() []Event { return observe(e, "F008D1789200", -75, 3) },
+		// office is stronger, but not long enough
+		func() []Event { return observe(e, "F008D1789201", -60, 4) },
+		func() []Event { return observe(e, "F008D1789200", -55, 5) },
+		// office is stronger for dwell time
+		func() []Event { return observe(e, "F008D1789201", -50, 6) },
+		func() []Event { return e.Tick(at(7)) },
+		func() []Event { return e.Tick(at(8)) },
+		// unknown gateway in range, but office is stronger
+		func() []Event { return observe(e, "F008D1789202", -69, 9) },
+		// absence timeout of office and lobby, F008D1789202 is the only one in range
+		func() []Event { return e.Tick(at(16)) },
+		func() []Event { return e.Tick(at(18)) },
+		// absence timeout of all zones
+		func() []Event { return e.Tick(at(19)) },
+	}
+	for _, step := range steps {
+		got = append(got, step()...)
+	}
+	want := []Event{
+		{Enter, beacon, "lobby", "", at(3)},
+		{ZoneChange, beacon, "office", "lobby", at(8)},
+		{ZoneChange, beacon, "F008D1789202", "office", at(18)},
+		{Exit, beacon, "F008D1789202", "", at(19)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%v\nwant\n%v", got, want)
+	}
+	if len(e.beacons) != 0 {
+		t.Errorf("beacon state not released")
+	}
+}
+
+func TestEngine_WeakSignalExit(t *testing.T) {
+	e := New(Config{EnterRSSI: -70, ExitRSSI: -80, AbsenceTimeout: time.Minute})
+	var got []Event
+	got = append(got, observe(e, "F008D1789200", -60, 0)...)
+	got = append(got, observe(e, "F008D1789200", -79, 1)...)
+	got = append(got, observe(e, "F008D1789200", -81, 2)...)
+	want := []Event{
+		{Enter, beacon, "F008D1789200", "", at(0)},
+		{Exit, beacon, "F008D1789200", "", at(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_MultiGatewayZone(t *testing.T) {
+	e := New(Config{
+		EnterRSSI: -70,
+		ExitRSSI:  -80,
+		Zones:     map[string]string{"F008D1789200": "lobby", "F008D1789201": "lobby"},
+	})
+	var got []Event
+	got = append(got, observe(e, "F008D1789200", -50, 0)...)
+	// weak reading of the other gateway of the zone
+	got = append(got, observe(e, "F008D1789201", -90, 0.1)...)
+	got = append(got, e.Tick(at(20))...)
+	// out of range for the default absence timeout
+	got = append(got, e.Tick(at(30))...)
+	want := []Event{
+		{Enter, beacon, "lobby", "", at(0)},
+		{Exit, beacon, "lobby", "", at(30)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_ZoneMAC(t *testing.T) {
+	e := New(Config{
+		EnterRSSI: -70,
+		ExitRSSI:  -80,
+		Zones:     map[string]string{"f0:08:d1:78:92:00": "lobby"},
+	})
+	got := observe(e, "F008D1789200", -50, 0)
+	got = append(got, e.Observe(igs.Parse("$GPRP,"+strings.ToLower(beacon)+",f008d1789201,-40,0201"))...)
+	want := []Event{
+		{Enter, beacon, "lobby", "", at(0)},
+		{ZoneChange, beacon, "F008D1789201", "lobby", at(0)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
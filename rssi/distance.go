@@ -0,0 +1,120 @@
+package rssi
+
+import (
+	"math"
+
+	"github.com/ingics/ingics-parser-go/ibs"
+)
+
+// Proximity classification of estimated distance
+type Proximity int
+
+const (
+	Unknown   Proximity = iota
+	Immediate           // within 0.5 meter
+	Near                // within 3 meters
+	Far
+)
+
+// Stringer of Proximity
+func (p Proximity) String() string {
+	switch p {
+	case Immediate:
+		return "Immediate"
+	case Near:
+		return "Near"
+	case Far:
+		return "Far"
+	}
+	return "Unknown"
+}
+
+// Proximity of the distance (in meters)
+func ProximityOf(distance float64) Proximity {
+	switch {
+	case distance < 0 || math.IsNaN(distance) || math.IsInf(distance, 0):
+		return Unknown
+	case distance < 0.5:
+		return Immediate
+	case distance < 3:
+		return Near
+	}
+	return Far
+}
+
+const (
+	defaultExponent = 2.0
+	defaultRefTx    = -59
+)
+
+// Default reference TX power (RSSI at 1 meter, in dBm) of iBS models that do not advertise one,
+// keyed by the names returned by ibs.Payload.ProductModel and overridden by Estimator.Models.
+// Empty until calibrated values from Ingics datasheets are available, entries could be added
+// in init of the application, it is not safe to modify while estimating.
+var DefaultModelRefTx = map[string]int{}
+
+// Estimated distance
+type Estimate struct {
+	// Distance in meters
+	Distance float64
+	// Proximity classification of distance
+	Proximity Proximity
+	// Reference TX power used, in dBm
+	RefTx int
+}
+
+// Distance estimator by log-distance path loss model
+//
+//	distance = 10 ^ ((RefTx - RSSI) / (10 * Exponent))
+type Estimator struct {
+	// Path loss exponent of the environment, 2 for free space and 2.5 to 4 for indoor.
+	// 2 if zero
+	Exponent float64
+	// Reference TX power (RSSI at 1 meter, in dBm), used if neither payload nor model has one.
+	// -59 if zero
+	RefTx int
+	// Reference TX power of product models calibrated for the deployment,
+	// keys are the names returned by ibs.Payload.ProductModel, overrides DefaultModelRefTx
+	Models map[string]int
+}
+
+// Distance (in meters) of the RSSI with reference TX power
+func (e Estimator) Distance(rssi float64, refTx int) float64 {
+	n := e.Exponent
+	if n <= 0 {
+		n = defaultExponent
+	}
+	return math.Pow(10, (float64(refTx)-rssi)/(10*n))
+}
+
+// Reference TX power of the payload, which is the one advertised by iBeacon,
+// or the one of product model in Estimator.Models or DefaultModelRefTx, or Estimator.RefTx
+func (e Estimator) RefTxOf(p *ibs.Payload) int {
+	if p != nil {
+		if refTx, ok := p.RefTx(); ok {
+			return refTx
+		}
+		if model, ok := p.ProductModel(); ok {
+			if refTx, ok := e.Models[model]; ok {
+				return refTx
+			}
+			if refTx, ok := DefaultModelRefTx[model]; ok {
+				return refTx
+			}
+		}
+	}
+	if e.RefTx != 0 {
+		return e.RefTx
+	}
+	return defaultRefTx
+}
+
+// Estimate distance of the RSSI with the payload of beacon, which could be nil
+func (e Estimator) Estimate(rssi float64, p *ibs.Payload) Estimate {
+	refTx := e.RefTxOf(p)
+	if rssi == Missing || rssi >= 0 {
+		return Estimate{-1, Unknown, refTx}
+	}
+	d := e.Distance(rssi, refTx)
+	return Estimate{d, ProximityOf(d), refTx}
+}
@@ -0,0 +1,61 @@
+package rssi
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/ingics/ingics-parser-go/ibs"
+)
+
+func parsePayload(s string) *ibs.Payload {
+	b, _ := hex.DecodeString(s)
+	return ibs.Parse(b)
+}
+
+func TestEstimator(t *testing.T) {
+	ibeacon := parsePayload("0201061AFF4C000215B9A5D27D56CC4E3AAB511F2153BCB9670000E9B2D6") // ref tx -42
+	ibs03t := parsePayload("02010612FF0D0083BC3101006D0B31000000140F0600")
+	ibs09r := parsePayload("0201061AFF2C0888BC470100AAAA74000000000000000000000042100000")
+
+	e := Estimator{Exponent: 2, RefTx: -65, Models: map[string]int{"iBS03T": -50}}
+	cases := []struct {
+		rssi      float64
+		payload   *ibs.Payload
+		refTx     int
+		distance  float64
+		proximity Proximity
+	}{
+		{-42, ibeacon, -42, 1, Near},
+		{-62, ibeacon, -42, 10, Far},
+		{-50, ibs03t, -50, 1, Near},
+		{-65, parsePayload("02010612FF2C0883BC290101AAAAFFFF000030000000"), -65, 1, Near},
+		{-45, ibs09r, -65, 0.1, Immediate},
+		{-65, nil, -65, 1, Near},
+		{Missing, nil, -65, -1, Unknown},
+	}
+	for _, c := range cases {
+		got := e.Estimate(c.rssi, c.payload)
+		if got.RefTx != c.refTx || math.Abs(got.Distance-c.distance) > 1e-9 || got.Proximity != c.proximity {
+			t.Errorf("Estimate(%v) = %+v, want %v, %v, %v", c.rssi, got, c.distance, c.proximity, c.refTx)
+		}
+	}
+
+	// indoor environment
+	e = Estimator{Exponent: 3}
+	if d := e.Distance(-89, -59); math.Abs(d-10) > 1e-9 {
+		t.Errorf("Distance() = %v, want 10", d)
+	}
+}
+
+func TestDefaultModelRefTx(t *testing.T) {
+	DefaultModelRefTx["iBS03T"] = -62
+	defer delete(DefaultModelRefTx, "iBS03T")
+	ibs03t := parsePayload("02010612FF0D0083BC3101006D0B31000000140F0600")
+	if got := (Estimator{RefTx: -65}).RefTxOf(ibs03t); got != -62 {
+		t.Errorf("RefTxOf() = %v, want -62", got)
+	}
+	if got := (Estimator{Models: map[string]int{"iBS03T": -50}}).RefTxOf(ibs03t); got != -50 {
+		t.Errorf("RefTxOf() = %v, want -50", got)
+	}
+}
@@ -0,0 +1,257 @@
+// Package rssi smooths noisy RSSI readings and estimates distance from them
+package rssi
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+	"github.com/ingics/ingics-parser-go/pipeline"
+)
+
+// RSSI value reported when the reading is not available
+const Missing = -127
+
+// Filter of RSSI readings of a beacon by a gateway
+type Filter interface {
+	// Update the filter with a reading at the time t, returns the filtered value
+	Update(rssi float64, t time.Time) float64
+	// The filtered value, false if not initialized
+	Value() (float64, bool)
+	// Discard the history
+	Reset()
+}
+
+const (
+	defaultTau    = 3 * time.Second
+	defaultWindow = 5 * time.Second
+)
+
+// Exponential moving average, the weight of a reading decays with time.
+// Readings not later than the last one are ignored.
+type EMA struct {
+	// Time constant, the weight of older value is exp(-dt/Tau), 3 seconds if not positive
+	Tau time.Duration
+	// Reset the filter if no reading for the duration, never if zero
+	MaxGap time.Duration
+
+	value float64
+	last  time.Time
+	init  bool
+}
+
+func (f *EMA) Update(rssi float64, t time.Time) float64 {
+	dt := t.Sub(f.last)
+	if !f.init || (f.MaxGap > 0 && dt > f.MaxGap) {
+		f.value, f.last, f.init = rssi, t, true
+		return f.value
+	}
+	if dt > 0 {
+		tau := f.Tau
+		if tau <= 0 {
+			tau = defaultTau
+		}
+		alpha := 1 - math.Exp(-float64(dt)/float64(tau))
+		f.value += alpha * (rssi - f.value)
+		f.last = t
+	}
+	return f.value
+}
+
+func (f *EMA) Value() (float64, bool) {
+	return f.value, f.init
+}
+
+func (f *EMA) Reset() {
+	f.init = false
+}
+
+type sample struct {
+	rssi float64
+	t    time.Time
+}
+
+// Median of readings in a sliding time window.
+// Readings earlier than the last one are ignored.
+type Median struct {
+	// Length of the window, 5 seconds if not positive
+	Window time.Duration
+
+	samples []sample
+}
+
+func (f *Median) Update(rssi float64, t time.Time) float64 {
+	if n := len(f.samples); n > 0 && t.Before(f.samples[n-1].t) {
+		v, _ := f.Value()
+		return v
+	}
+	f.samples = append(f.samples, sample{rssi, t})
+	window := f.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+	// drop samples out of window, which are sorted by time
+	i := 0
+	for i < len(f.samples)-1 && t.Sub(f.samples[i].t) > window {
+		i++
+	}
+	f.samples = append(f.samples[:0], f.samples[i:]...)
+	v, _ := f.Value()
+	return v
+}
+
+func (f *Median) Value() (float64, bool) {
+	n := len(f.samples)
+	if n == 0 {
+		return 0, false
+	}
+	x := make([]float64, n)
+	for i, s := range f.samples {
+		x[i] = s.rssi
+	}
+	sort.Float64s(x)
+	if n%2 == 1 {
+		return x[n/2], true
+	}
+	return (x[n/2-1] + x[n/2]) / 2, true
+}
+
+func (f *Median) Reset() {
+	f.samples = nil
+}
+
+const (
+	defaultKalmanQ = 0.5
+	defaultKalmanR = 4.0
+)
+
+// 1-D Kalman filter with constant value model
+type Kalman struct {
+	// Process noise variance per second, how fast the true RSSI changes.
+	// 0.5 if not positive
+	Q float64
+	// Measurement noise variance, 4 if not positive
+	R float64
+
+	value float64
+	p     float64 // estimate variance
+	last  time.Time
+	init  bool
+}
+
+func (f *Kalman) Update(rssi float64, t time.Time) float64 {
+	q, r := f.Q, f.R
+	if q <= 0 {
+		q = defaultKalmanQ
+	}
+	if r <= 0 {
+		r = defaultKalmanR
+	}
+	if !f.init {
+		f.value, f.p, f.last, f.init = rssi, r, t, true
+		return f.value
+	}
+	// uncertainty grows with the gap between readings
+	if dt := t.Sub(f.last).Seconds(); dt > 0 {
+		f.p += q * dt
+		f.last = t
+	}
+	k := f.p / (f.p + r)
+	f.value += k * (rssi - f.value)
+	f.p *= 1 - k
+	return f.value
+}
+
+func (f *Kalman) Value() (float64, bool) {
+	return f.value, f.init
+}
+
+func (f *Kalman) Reset() {
+	f.init = false
+}
+
+type pair struct {
+	beacon  string
+	gateway string
+}
+
+func newPair(beacon, gateway string) pair {
+	beacon, _ = igs.NormalizeMAC(beacon)
+	gateway, _ = igs.NormalizeMAC(gateway)
+	return pair{beacon, gateway}
+}
+
+// Filters of each beacon and gateway pair, safe for concurrent use.
+// Mac addresses are normalized by igs.NormalizeMAC.
+type Bank struct {
+	factory func() Filter
+	mu      sync.Mutex
+	filters map[pair]Filter
+}
+
+// Bank constructor, factory creates the filter for each new pair
+func NewBank(factory func() Filter) *Bank {
+	return &Bank{factory: factory, filters: map[pair]Filter{}}
+}
+
+// Update the filter of the message's beacon and gateway.
+// Time of message is the timestamp in message, or the received time if gateway sends none.
+// Missing readings (-127) are ignored, the current filtered value is returned.
+func (b *Bank) Update(m *igs.Message) (float64, bool) {
+	return b.UpdateValue(m.Beacon(), m.Gateway(), m.RSSI(), m.Time())
+}
+
+// Update the filter of the beacon and gateway pair with the reading
+func (b *Bank) UpdateValue(beacon, gateway string, rssi int, t time.Time) (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	k := newPair(beacon, gateway)
+	f, ok := b.filters[k]
+	if !ok {
+		f = b.factory()
+		b.filters[k] = f
+	}
+	if rssi == Missing {
+		return f.Value()
+	}
+	return f.Update(float64(rssi), t), true
+}
+
+// Filtered value of the beacon and gateway pair
+func (b *Bank) Value(beacon, gateway string) (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if f, ok := b.filters[newPair(beacon, gateway)]; ok {
+		return f.Value()
+	}
+	return 0, false
+}
+
+// Pipeline stage replaces RSSI of reports with the filtered value
+func (b *Bank) Stage() pipeline.Stage {
+	return pipeline.StageFunc(func(ctx context.Context, r *igs.Report) bool {
+		t := r.Received
+		if r.Timestamp != nil {
+			t = *r.Timestamp
+		}
+		if v, ok := b.UpdateValue(r.Beacon, r.Gateway, r.RSSI, t); ok {
+			r.RSSI = int(math.Round(v))
+		}
+		return true
+	})
+}
+
+// Remove filters of the beacon
+func (b *Bank) Remove(beacon string) {
+	beacon, _ = igs.NormalizeMAC(beacon)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for k := range b.filters {
+		if k.beacon == beacon {
+			delete(b.filters, k)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package rssi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+var t0 = time.Unix(1630382368, 0)
+
+func at(sec float64) time.Time {
+	return t0.Add(time.Duration(sec * float64(time.Second)))
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestEMA(t *testing.T) {
+	f := &EMA{Tau: time.Second, MaxGap: 10 * time.Second}
+	if _, ok := f.Value(); ok {
+		t.Errorf("Value() of empty filter ok")
+	}
+	if v := f.Update(-60, at(0)); v != -60 {
+		t.Errorf("Update() = %v, want -60", v)
+	}
+	// weight of new reading is 1-exp(-1)
+	if v := f.Update(-70, at(1)); !near(v, -60-10*(1-math.Exp(-1))) {
+		t.Errorf("Update() = %v", v)
+	}
+	// weight of new reading is higher after longer gap
+	g := &EMA{Tau: time.Second}
+	g.Update(-60, at(0))
+	if v := g.Update(-70, at(3)); !near(v, -60-10*(1-math.Exp(-3))) {
+		t.Errorf("Update() = %v", v)
+	}
+	// reset after max gap
+	if v := f.Update(-80, at(20)); v != -80 {
+		t.Errorf("Update() after gap = %v, want -80", v)
+	}
+	// zero value smooths with the default Tau
+	var z EMA
+	z.Update(-60, at(0))
+	if v := z.Update(-70, at(3)); !near(v, -60-10*(1-math.Exp(-1))) {
+		t.Errorf("Update() of zero value = %v", v)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	f := &Median{Window: 2 * time.Second}
+	values := []float64{-60, -90, -62, -61, -100}
+	want := []float64{-60, -75, -62, -62, -62}
+	for i, v := range values {
+		if got := f.Update(v, at(float64(i))); got != want[i] {
+			t.Errorf("Update(%v) = %v, want %v", v, got, want[i])
+		}
+	}
+	// samples out of window are dropped after gap
+	if got := f.Update(-50, at(100)); got != -50 {
+		t.Errorf("Update() after gap = %v, want -50", got)
+	}
+	// out of order reading is ignored
+	if got := f.Update(-90, at(99)); got != -50 {
+		t.Errorf("Update() out of order = %v, want -50", got)
+	}
+	// zero value keeps the default window
+	var z Median
+	for i, v := range []float64{-60, -90, -62} {
+		z.Update(v, at(float64(i)))
+	}
+	if got, _ := z.Value(); got != -62 {
+		t.Errorf("Value() of zero value = %v, want -62", got)
+	}
+}
+
+func TestKalman(t *testing.T) {
+	f := &Kalman{Q: 0.5, R: 4}
+	f.Update(-60, at(0))
+	v := f.Update(-70, at(1))
+	// p = 4 + 0.5, k = 4.5 / 8.5
+	if !near(v, -60-10*4.5/8.5) {
+		t.Errorf("Update() = %v", v)
+	}
+	// trust new reading more after long gap
+	g := &Kalman{Q: 0.5, R: 4}
+	g.Update(-60, at(0))
+	if w := g.Update(-70, at(100)); w >= v {
+		t.Errorf("Update() after gap = %v, want < %v", w, v)
+	}
+	// defaults of zero value, same as Q 0.5 and R 4
+	var z Kalman
+	z.Update(-60, at(0))
+	if w := z.Update(-70, at(1)); !near(w, v) {
+		t.Errorf("Update() of zero value = %v, want %v", w, v)
+	}
+}
+
+func TestBank(t *testing.T) {
+	b := NewBank(func() Filter { return &Median{Window: time.Minute} })
+	lines := []struct {
+		gateway string
+		rssi    int
+	}{
+		{"F008D1789200", -60},
+		{"F008D1789200", -127},
+		{"F008D1789200", -70},
+		{"F008D1789201", -80},
+	}
+	for i, l := range lines {
+		b.Update(igs.Parse(fmt.Sprintf("$GPRP,D8714D784B4F,%v,%v,0201,%v", l.gateway, l.rssi, t0.Unix()+int64(i))))
+	}
+	if v, ok := b.Value("D8714D784B4F", "F008D1789200"); !ok || v != -65 {
+		t.Errorf("Value() = %v, %v, want -65", v, ok)
+	}
+	if v, ok := b.Value("D8714D784B4F", "F008D1789201"); !ok || v != -80 {
+		t.Errorf("Value() = %v, %v, want -80", v, ok)
+	}
+	if _, ok := b.UpdateValue("D8714D784B40", "F008D1789200", Missing, t0); ok {
+		t.Errorf("UpdateValue() of missing reading ok")
+	}
+
+	r, _ := igs.Parse(fmt.Sprintf("$GPRP,D8714D784B4F,F008D1789201,-90,0201,%v", t0.Unix()+4)).Decode()
+	b.Stage().Process(context.Background(), r)
+	if r.RSSI != -85 {
+		t.Errorf("Stage() RSSI = %v, want -85", r.RSSI)
+	}
+
+	// mac addresses are normalized
+	if v, ok := b.Value("d8:71:4d:78:4b:4f", "f0:08:d1:78:92:01"); !ok || v != -85 {
+		t.Errorf("Value() = %v, %v, want -85", v, ok)
+	}
+
+	b.Remove("d8714d784b4f")
+	if _, ok := b.Value("D8714D784B4F", "F008D1789200"); ok {
+		t.Errorf("Value() after Remove() ok")
+	}
+}
@@ -0,0 +1,219 @@
+// Package tracker keeps the latest state of each beacon
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/ibs"
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+// Last reading of a beacon by a gateway
+type GatewayReading struct {
+	RSSI     int
+	LastSeen time.Time
+}
+
+// Last sensor readings of a beacon, nil if never reported
+type Readings = ibs.Readings
+
+// State of a beacon
+type State struct {
+	// Beacon (Tag) BLE mac address
+	Beacon string
+	// Time of the first and the last message of the beacon
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Vendor and product model, empty if never known
+	Vendor string
+	Model  string
+	// Last reading of each gateway, indexed by gateway mac address
+	Gateways map[string]GatewayReading
+	// Last sensor readings
+	Readings Readings
+}
+
+// Deep copy, the state is shared with subscribers and snapshot callers
+func (s *State) clone() State {
+	x := *s
+	x.Gateways = make(map[string]GatewayReading, len(s.Gateways))
+	for k, v := range s.Gateways {
+		x.Gateways[k] = v
+	}
+	x.Readings = s.Readings.Clone()
+	return x
+}
+
+// Kind of state change
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Updated
+	Removed
+)
+
+// State change notified to subscribers
+type Change struct {
+	Kind ChangeKind
+	// The new state, or the last state for Removed
+	State State
+}
+
+// Tracker keeps the latest state of each beacon, safe for concurrent use
+type Tracker struct {
+	mu     sync.RWMutex
+	states map[string]*State
+	subs   map[int]func(Change)
+	nextID int
+}
+
+// Tracker constructor
+func New() *Tracker {
+	return &Tracker{
+		states: map[string]*State{},
+		subs:   map[int]func(Change){},
+	}
+}
+
+// Update the state of beacon by the message and its parsed payload.
+// Time of message is the timestamp in message, or the received time if gateway sends none.
+func (t *Tracker) Update(m *igs.Message, p *ibs.Payload) {
+	t.update(m.Beacon(), m.Gateway(), m.RSSI(), m.Time(), p)
+}
+
+// Update the state of beacon by the decoded report
+func (t *Tracker) UpdateReport(r *igs.Report) {
+	now := r.Received
+	if r.Timestamp != nil {
+		now = *r.Timestamp
+	}
+	t.update(r.Beacon, r.Gateway, r.RSSI, now, r.Payload)
+}
+
+// Mac addresses are normalized by igs.NormalizeMAC
+func (t *Tracker) update(beacon, gateway string, rssi int, now time.Time, p *ibs.Payload) {
+	beacon, _ = igs.NormalizeMAC(beacon)
+	gateway, _ = igs.NormalizeMAC(gateway)
+	t.mu.Lock()
+	st, ok := t.states[beacon]
+	kind := Updated
+	if !ok {
+		kind = Added
+		st = &State{
+			Beacon:    beacon,
+			FirstSeen: now,
+			Gateways:  map[string]GatewayReading{},
+		}
+		t.states[beacon] = st
+	}
+	if now.After(st.LastSeen) {
+		st.LastSeen = now
+	}
+	st.Gateways[gateway] = GatewayReading{rssi, now}
+	if p != nil {
+		if vendor, ok := p.Vendor(); ok {
+			st.Vendor = vendor
+		}
+		if model, ok := p.ProductModel(); ok {
+			st.Model = model
+		}
+		st.Readings.Merge(p.Readings())
+	}
+	change := Change{kind, st.clone()}
+	subs := t.subscribers()
+	t.mu.Unlock()
+	for _, f := range subs {
+		f(change)
+	}
+}
+
+// State of the beacon, separators ':' and '-' are allowed in mac address
+func (t *Tracker) Get(beacon string) (State, bool) {
+	beacon, _ = igs.NormalizeMAC(beacon)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if st, ok := t.states[beacon]; ok {
+		return st.clone(), true
+	}
+	return State{}, false
+}
+
+// Number of tracked beacons
+func (t *Tracker) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.states)
+}
+
+// States of all beacons, sorted by beacon mac address
+func (t *Tracker) Snapshot() []State {
+	t.mu.RLock()
+	x := make([]State, 0, len(t.states))
+	for _, st := range t.states {
+		x = append(x, st.clone())
+	}
+	t.mu.RUnlock()
+	sort.Slice(x, func(i, j int) bool { return x[i].Beacon < x[j].Beacon })
+	return x
+}
+
+// Calls f for each beacon state until f returns false, in the order of Snapshot
+func (t *Tracker) Range(f func(State) bool) {
+	for _, st := range t.Snapshot() {
+		if !f(st) {
+			return
+		}
+	}
+}
+
+// Remove beacons not seen since the time, returns the removed states
+func (t *Tracker) Expire(since time.Time) []State {
+	var removed []State
+	t.mu.Lock()
+	for beacon, st := range t.states {
+		if st.LastSeen.Before(since) {
+			removed = append(removed, st.clone())
+			delete(t.states, beacon)
+		}
+	}
+	subs := t.subscribers()
+	t.mu.Unlock()
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Beacon < removed[j].Beacon })
+	for _, st := range removed {
+		for _, f := range subs {
+			f(Change{Removed, st})
+		}
+	}
+	return removed
+}
+
+// Subscribe state changes, f is called synchronously by the updating goroutine.
+// Returns the function to cancel the subscription.
+func (t *Tracker) Subscribe(f func(Change)) (cancel func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := t.nextID
+	t.nextID++
+	t.subs[id] = f
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.subs, id)
+	}
+}
+
+func (t *Tracker) subscribers() []func(Change) {
+	ids := make([]int, 0, len(t.subs))
+	for id := range t.subs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	x := make([]func(Change), len(ids))
+	for i, id := range ids {
+		x[i] = t.subs[id]
+	}
+	return x
+}
@@ -0,0 +1,114 @@
+package tracker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ingics/ingics-parser-go/igs"
+)
+
+func update(t *testing.T, tr *Tracker, line string) {
+	r, err := igs.Parse(line).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.UpdateReport(r)
+}
+
+func TestTracker(t *testing.T) {
+	tr := New()
+	var changes []Change
+	cancel := tr.Subscribe(func(c Change) { changes = append(changes, c) })
+
+	// iBS03T reading
+	update(t, tr, "$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600,1630382368")
+	// iBS03T reading without humidity, heard by another gateway
+	update(t, tr, "$GPRP,D8714D784B4F,F008D1789201,-60,02010612FF0D0083BC2801020A09FFFF000015030000,1630382369")
+	// iBeacon
+	update(t, tr, "$GPRP,D8714D784B40,F008D1789200,-70,0201061AFF4C000215E2C56DB5DFFB48D2B060D0F5A71096E000000000C5,1630382370")
+
+	st, ok := tr.Get("D8714D784B4F")
+	if !ok {
+		t.Fatal("Get() not found")
+	}
+	if st.Model != "iBS03T" || st.Vendor != "INGICS TECHNOLOGY CO., LTD." {
+		t.Errorf("Model, Vendor = %v, %v", st.Model, st.Vendor)
+	}
+	if !st.FirstSeen.Equal(time.Unix(1630382368, 0)) || !st.LastSeen.Equal(time.Unix(1630382369, 0)) {
+		t.Errorf("FirstSeen, LastSeen = %v, %v", st.FirstSeen, st.LastSeen)
+	}
+	if len(st.Gateways) != 2 || st.Gateways["F008D1789200"].RSSI != -45 || st.Gateways["F008D1789201"].RSSI != -60 {
+		t.Errorf("Gateways = %v", st.Gateways)
+	}
+	// temperature updated by the second reading, humidity kept from the first one
+	if r := st.Readings; *r.Temperature != 23.14 || *r.Humidity != 49 || *r.Battery != 2.96 || *r.Button {
+		t.Errorf("Readings = %v, %v, %v", *r.Temperature, *r.Humidity, *r.Battery)
+	}
+	if st.Readings.Range != nil || st.Readings.Accel != nil {
+		t.Errorf("unexpected readings = %+v", st.Readings)
+	}
+	// mac addresses are normalized
+	update(t, tr, "$GPRP,d8714d784b4f,f008d1789201,-61,0201,1630382369")
+	if st, ok := tr.Get("D8:71:4D:78:4B:4F"); !ok || len(st.Gateways) != 2 || st.Gateways["F008D1789201"].RSSI != -61 {
+		t.Errorf("Get() = %+v, %v", st, ok)
+	}
+	// state got is a deep copy
+	*st.Readings.Temperature = 0
+	if st, _ := tr.Get("D8714D784B4F"); *st.Readings.Temperature != 23.14 {
+		t.Errorf("Temperature = %v, want 23.14", *st.Readings.Temperature)
+	}
+
+	// snapshot is not affected by later updates
+	snapshot := tr.Snapshot()
+	update(t, tr, "$GPRP,D8714D784B4F,F008D1789202,-50,0201,1630382371")
+	if len(snapshot) != 2 || snapshot[0].Beacon != "D8714D784B40" || len(snapshot[1].Gateways) != 2 {
+		t.Errorf("Snapshot() = %+v", snapshot)
+	}
+	n := 0
+	tr.Range(func(st State) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Range() called %v times, want 1", n)
+	}
+
+	removed := tr.Expire(time.Unix(1630382371, 0))
+	if len(removed) != 1 || removed[0].Beacon != "D8714D784B40" || tr.Len() != 1 {
+		t.Errorf("Expire() = %+v, Len() = %v", removed, tr.Len())
+	}
+
+	cancel()
+	update(t, tr, "$GPRP,D8714D784B41,F008D1789200,-45,0201,1630382372")
+	kinds := []ChangeKind{Added, Updated, Added, Updated, Updated, Removed}
+	if len(changes) != len(kinds) {
+		t.Fatalf("%v changes notified, want %v", len(changes), len(kinds))
+	}
+	for i, c := range changes {
+		if c.Kind != kinds[i] {
+			t.Errorf("changes[%v].Kind = %v, want %v", i, c.Kind, kinds[i])
+		}
+	}
+}
+
+func TestTracker_Concurrent(t *testing.T) {
+	tr := New()
+	m := igs.Parse("$GPRP,D8714D784B4F,F008D1789200,-45,02010612FF0D0083BC3101006D0B31000000140F0600")
+	r, _ := m.Decode()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tr.Update(m, r.Payload)
+				tr.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+	if tr.Len() != 1 {
+		t.Errorf("Len() = %v", tr.Len())
+	}
+}